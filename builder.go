@@ -0,0 +1,82 @@
+package imgproxyurl
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Builder holds the configuration shared by the urls it creates: key, salt, endpoint, signature size
+// and any other options (including processing options) all the urls should have.
+//
+// A Builder is immutable and safe for concurrent use. WithOptions returns a new Builder.
+type Builder struct {
+	base *Url
+}
+
+// NewBuilder creates a Builder with the given options.
+func NewBuilder(options ...Option) (*Builder, error) {
+	base, err := (&Url{}).clone(options)
+	if err != nil {
+		return nil, err
+	}
+	return &Builder{base: base}, nil
+}
+
+// New creates a url for sourceUrl with the options of the Builder and the options passed.
+func (b *Builder) New(sourceUrl string, options ...Option) (*Url, error) {
+	result, err := b.base.clone(options)
+	if err != nil {
+		return nil, err
+	}
+	result.sourceUrl = sourceUrl
+	return result, nil
+}
+
+// WithOptions returns a copy of the Builder with the options applied. The Builder itself is not modified.
+func (b *Builder) WithOptions(options ...Option) (*Builder, error) {
+	base, err := b.base.clone(options)
+	if err != nil {
+		return nil, err
+	}
+	return &Builder{base: base}, nil
+}
+
+var (
+	// std is the Builder used by the package-level functions. It holds a *Builder.
+	std atomic.Value
+	// stdMu serializes updates of std, so that concurrent Set* calls don't lose each other's changes.
+	stdMu sync.Mutex
+)
+
+func init() {
+	std.Store(&Builder{base: &Url{}})
+}
+
+// Default returns the Builder used by the package-level functions (New, Parse, etc.).
+func Default() *Builder {
+	return std.Load().(*Builder)
+}
+
+// SetDefault replaces the Builder used by the package-level functions.
+// It is safe to call concurrently with New, Parse and the other package-level functions.
+// A nil Builder (or a zero one not created with NewBuilder) is ignored and the current one is kept.
+func SetDefault(b *Builder) {
+	if b == nil || b.base == nil {
+		return
+	}
+	stdMu.Lock()
+	defer stdMu.Unlock()
+	std.Store(b)
+}
+
+// updateDefault applies options to the default Builder. On error the default Builder is left unchanged.
+func updateDefault(options ...Option) error {
+	stdMu.Lock()
+	defer stdMu.Unlock()
+	b, err := Default().WithOptions(options...)
+	if err != nil {
+		return err
+	}
+	std.Store(b)
+	return nil
+}
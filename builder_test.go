@@ -0,0 +1,93 @@
+package imgproxyurl
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	b, err := NewBuilder(Key{testKey}, Salt{testSalt}, Endpoint{"https://example.com/"}, ResizingType{ResizingTypeFill})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := b.New("local:///o/t/otRO1jl3IUVa.jpg", Width{200}, Height{200}, Format{"png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "https://example.com/Yysx5pZ_gcWJbVQEHSp37U6r3swrZgFAygnHmbFK2VE/h:200/rt:fill/w:200/bG9jYWw6Ly8vby90L290Uk8xamwzSVVWYS5qcGc.png"
+	if got := u.String(); got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+
+	b2, err := b.WithOptions(Endpoint{"https://example2.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u2, _ := b.New("local:///a.jpg"); u2.Endpoint() != "https://example.com/" {
+		t.Errorf("WithOptions() modified the original builder")
+	}
+	if u2, _ := b2.New("local:///a.jpg"); u2.Endpoint() != "https://example2.com" {
+		t.Errorf("WithOptions() endpoint = %v, want https://example2.com", u2.Endpoint())
+	}
+
+	if _, err := b.WithOptions(Key{"not hex"}); err == nil {
+		t.Errorf("WithOptions() expected an error")
+	}
+}
+
+func TestSetKeySalt_error(t *testing.T) {
+	defer SetDefault(Default())
+
+	if err := SetKeySalt(testKey, testSalt); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetKeySalt(testSalt, "not hex"); err == nil {
+		t.Fatal("SetKeySalt() expected an error")
+	}
+	u, err := New("local:///o/t/otRO1jl3IUVa.jpg", Width{200}, Height{200}, Format{"png"}, ResizingType{ResizingTypeFill}, Endpoint{"https://example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the key must not be half-updated by the failed call
+	const want = "https://example.com/Yysx5pZ_gcWJbVQEHSp37U6r3swrZgFAygnHmbFK2VE/h:200/rt:fill/w:200/bG9jYWw6Ly8vby90L290Uk8xamwzSVVWYS5qcGc.png"
+	if got := u.String(); got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+}
+
+func TestSetDefault_nil(t *testing.T) {
+	defer SetDefault(Default())
+
+	SetDefault(nil)
+	SetDefault(&Builder{})
+	if _, err := New("local:///a.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Parse("/insecure/w:100/bG9jYWw6Ly8vYS5qcGc"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDefault_concurrent(t *testing.T) {
+	defer SetDefault(Default())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			SetEndpoint("https://example.com/")
+			_ = SetKeySalt(testKey, testSalt)
+		}()
+		go func() {
+			defer wg.Done()
+			u, err := New("local:///a.jpg", Width{100})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			_ = u.String()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,379 @@
+// Command imgproxyurl generates, signs and inspects imgproxy urls.
+//
+// Usage:
+//
+//	imgproxyurl sign [flags] <source url>
+//	imgproxyurl verify [flags] <imgproxy url>
+//	imgproxyurl decode [flags] <imgproxy url>
+//	imgproxyurl explain [flags] <imgproxy url>
+//	imgproxyurl keygen
+//
+// Key, salt, signature size and endpoint are read from the flags or from the imgproxy environment variables
+// (IMGPROXY_KEY, IMGPROXY_SALT, IMGPROXY_SIGNATURE_SIZE, IMGPROXY_ENDPOINT, see imgproxyurl.ConfigFromEnv).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/penyaev/imgproxyurl"
+	"io"
+	"os"
+	"strings"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+const usage = `usage: imgproxyurl <command> [flags] <url>
+
+commands:
+  sign     build a signed url from a source url and option flags
+  verify   check the signature of an imgproxy url
+  decode   print the source url, format and options of an imgproxy url
+  explain  describe the processing options of an imgproxy url
+  keygen   generate a random key and salt
+
+run "imgproxyurl <command> -help" for the command flags
+`
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var cmd func(*config, []string, io.Writer) error
+	switch args[0] {
+	case "sign":
+		cmd = sign
+	case "verify":
+		cmd = verify
+	case "decode":
+		cmd = decode
+	case "explain":
+		cmd = explain
+	case "keygen":
+		if err := keygen(stdout); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", args[0], err)
+			return 1
+		}
+		return 0
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	c := newConfig(args[0])
+	c.flags.SetOutput(stderr)
+	if err := c.flags.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if c.flags.NArg() != 1 {
+		fmt.Fprintf(stderr, "%s: exactly one url argument is required\n", args[0])
+		return 2
+	}
+
+	env, err := imgproxyurl.ConfigFromEnv(imgproxyurl.DefaultEnvPrefix)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", args[0], err)
+		return 1
+	}
+	c.env = env
+
+	if err := cmd(c, c.flags.Args(), stdout); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+type config struct {
+	flags *flag.FlagSet
+	env   []imgproxyurl.Option
+
+	key           string
+	salt          string
+	endpoint      string
+	signatureSize int
+	strict        bool
+
+	width             int
+	height            int
+	resizingType      string
+	resizingAlgorithm string
+	dpr               int
+	quality           int
+	enlarge           bool
+	format            string
+	plain             bool
+	raw               rawOptions
+}
+
+func newConfig(name string) *config {
+	c := &config{flags: flag.NewFlagSet(name, flag.ContinueOnError)}
+	c.flags.StringVar(&c.key, "key", "", "hex-encoded key (defaults to $IMGPROXY_KEY)")
+	c.flags.StringVar(&c.salt, "salt", "", "hex-encoded salt (defaults to $IMGPROXY_SALT)")
+	c.flags.StringVar(&c.endpoint, "endpoint", "", "imgproxy endpoint, e.g. https://imgproxy.example.com (defaults to $IMGPROXY_ENDPOINT)")
+	c.flags.IntVar(&c.signatureSize, "signature-size", 0, "signature size in bytes (defaults to $IMGPROXY_SIGNATURE_SIZE)")
+
+	if name == "sign" {
+		c.flags.BoolVar(&c.strict, "strict", true, "validate the options")
+		c.flags.IntVar(&c.width, "w", 0, "width")
+		c.flags.IntVar(&c.height, "h", 0, "height")
+		c.flags.StringVar(&c.resizingType, "rt", "", "resizing type: fit, fill, fill-down, force or auto")
+		c.flags.StringVar(&c.resizingAlgorithm, "ra", "", "resizing algorithm: nearest, linear, cubic, lanczos2 or lanczos3")
+		c.flags.IntVar(&c.dpr, "dpr", 0, "device pixel ratio")
+		c.flags.IntVar(&c.quality, "q", 0, "quality")
+		c.flags.BoolVar(&c.enlarge, "el", false, "enlarge")
+		c.flags.StringVar(&c.format, "format", "", "resulting image format, e.g. webp")
+		c.flags.BoolVar(&c.plain, "plain", false, "don't encode the source url")
+		c.flags.Var(&c.raw, "o", "arbitrary processing option in key:arguments form, can be repeated")
+	}
+
+	return c
+}
+
+// baseOptions returns the options shared by all commands: the environment configuration overridden by the flags.
+func (c *config) baseOptions() []imgproxyurl.Option {
+	options := append([]imgproxyurl.Option(nil), c.env...)
+	set := c.setFlags()
+	// the key and the salt are overridden separately, so that e.g. -key can be combined with $IMGPROXY_SALT
+	if set["key"] {
+		options = append(options, imgproxyurl.Key{Key: c.key})
+	}
+	if set["salt"] {
+		options = append(options, imgproxyurl.Salt{Salt: c.salt})
+	}
+	if set["endpoint"] {
+		options = append(options, imgproxyurl.Endpoint{Endpoint: c.endpoint})
+	}
+	if set["signature-size"] {
+		options = append(options, imgproxyurl.SignatureSize{SignatureSize: c.signatureSize})
+	}
+	return options
+}
+
+func (c *config) setFlags() map[string]bool {
+	set := make(map[string]bool)
+	c.flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
+
+// processingOptions returns the options set by the flags of the sign command.
+func (c *config) processingOptions() []imgproxyurl.Option {
+	options := []imgproxyurl.Option{imgproxyurl.Strict{Strict: c.strict}, imgproxyurl.PlainSourceUrl{Plain: c.plain}}
+	set := c.setFlags()
+
+	if set["w"] {
+		options = append(options, imgproxyurl.Width{W: c.width})
+	}
+	if set["h"] {
+		options = append(options, imgproxyurl.Height{H: c.height})
+	}
+	if set["rt"] {
+		options = append(options, imgproxyurl.ResizingType{ResizingType: imgproxyurl.ResizingTypeName(c.resizingType)})
+	}
+	if set["ra"] {
+		options = append(options, imgproxyurl.ResizingAlgorithm{ResizingAlgorithm: imgproxyurl.ResizingAlgorithmName(c.resizingAlgorithm)})
+	}
+	if set["dpr"] {
+		options = append(options, imgproxyurl.Dpr{Dpr: c.dpr})
+	}
+	if set["q"] {
+		options = append(options, imgproxyurl.Quality{Quality: c.quality})
+	}
+	if set["el"] {
+		options = append(options, imgproxyurl.Enlarge{Enlarge: c.enlarge})
+	}
+	if set["format"] {
+		options = append(options, imgproxyurl.Format{Format: imgproxyurl.FormatName(c.format)})
+	}
+	for _, raw := range c.raw {
+		options = append(options, raw)
+	}
+
+	return options
+}
+
+// keygen prints a random key and salt in the environment variables format.
+func keygen(stdout io.Writer) error {
+	key, salt, err := imgproxyurl.GenerateKeySalt()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%sKEY=%s\n%sSALT=%s\n", imgproxyurl.DefaultEnvPrefix, key, imgproxyurl.DefaultEnvPrefix, salt)
+	return nil
+}
+
+func sign(c *config, args []string, stdout io.Writer) error {
+	u, err := imgproxyurl.New(args[0], append(c.baseOptions(), c.processingOptions()...)...)
+	if err != nil {
+		return err
+	}
+	result, err := u.Build()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, result)
+	return nil
+}
+
+func verify(c *config, args []string, stdout io.Writer) error {
+	u, err := imgproxyurl.New("", c.baseOptions()...)
+	if err != nil {
+		return err
+	}
+	if !u.Signed() {
+		return fmt.Errorf("key and salt are required")
+	}
+	if err := u.Verify(args[0]); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "OK")
+	return nil
+}
+
+func decode(c *config, args []string, stdout io.Writer) error {
+	u, err := imgproxyurl.Parse(args[0], c.baseOptions()...)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "endpoint: %s\n", u.Endpoint())
+	fmt.Fprintf(stdout, "source:   %s\n", u.SourceUrl())
+	fmt.Fprintf(stdout, "format:   %s\n", u.Format())
+	fmt.Fprintf(stdout, "options:\n")
+	for _, option := range u.Options() {
+		fmt.Fprintf(stdout, "  %s\n", option)
+	}
+	return nil
+}
+
+// optionNames maps processing option keys to their full names from the imgproxy docs.
+var optionNames = map[string]string{
+	"rs":    "resize",
+	"s":     "size",
+	"rt":    "resizing_type",
+	"ra":    "resizing_algorithm",
+	"w":     "width",
+	"h":     "height",
+	"dpr":   "dpr",
+	"el":    "enlarge",
+	"ex":    "extend",
+	"g":     "gravity",
+	"c":     "crop",
+	"pd":    "padding",
+	"t":     "trim",
+	"rot":   "rotate",
+	"q":     "quality",
+	"mb":    "max_bytes",
+	"bg":    "background",
+	"bga":   "background_alpha",
+	"bl":    "blur",
+	"sh":    "sharpen",
+	"pr":    "preset",
+	"ar":    "auto_rotate",
+	"fn":    "filename",
+	"wm":    "watermark",
+	"wmu":   "watermark_url",
+	"wmt":   "watermark_text",
+	"wms":   "watermark_size",
+	"wmr":   "watermark_rotate",
+	"wmsh":  "watermark_shadow",
+	"br":    "brightness",
+	"co":    "contrast",
+	"sa":    "saturation",
+	"a":     "adjust",
+	"mc":    "monochrome",
+	"dt":    "duotone",
+	"col":   "colorize",
+	"jpgo":  "jpeg_options",
+	"pngo":  "png_options",
+	"webpo": "webp_options",
+	"avifo": "avif_options",
+	"gifo":  "gif_options",
+	"fq":    "format_quality",
+	"aq":    "autoquality",
+	"f":     "format",
+	"bf":    "best_format",
+	"sm":    "strip_metadata",
+	"kcr":   "keep_copyright",
+	"scp":   "strip_color_profile",
+	"eth":   "enforce_thumbnail",
+	"dpi":   "dpi",
+}
+
+func explain(c *config, args []string, stdout io.Writer) error {
+	u, err := imgproxyurl.Parse(args[0], c.baseOptions()...)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !u.Signed():
+		fmt.Fprintln(stdout, "signature: not checked (no key/salt)")
+	default:
+		if err := u.Verify(args[0]); err != nil {
+			fmt.Fprintf(stdout, "signature: invalid (%v)\n", err)
+		} else {
+			fmt.Fprintln(stdout, "signature: valid")
+		}
+	}
+
+	fmt.Fprintf(stdout, "source image %q\n", u.SourceUrl())
+	for _, option := range u.Options() {
+		key, arguments := option, ""
+		if i := strings.IndexByte(option, ':'); i >= 0 {
+			key, arguments = option[:i], option[i+1:]
+		}
+		name, ok := optionNames[key]
+		if !ok {
+			name = "unknown option"
+			// parsed urls keep the full names
+			for short, full := range optionNames {
+				if full == key {
+					key, name = short, full
+					break
+				}
+			}
+		}
+		fmt.Fprintf(stdout, "%-4s %-19s %s\n", key, name, strings.ReplaceAll(arguments, ":", " "))
+	}
+	if u.Format() != "" {
+		fmt.Fprintf(stdout, "converted to %s\n", u.Format())
+	}
+	return nil
+}
+
+// rawOptions collects repeated -o flags.
+type rawOptions []imgproxyurl.Raw
+
+func (r *rawOptions) String() string {
+	var ss []string
+	for _, raw := range *r {
+		ss = append(ss, raw.Key()+":"+raw.String())
+	}
+	return strings.Join(ss, "/")
+}
+
+func (r *rawOptions) Set(value string) error {
+	parts := strings.Split(value, ":")
+	if parts[0] == "" {
+		return fmt.Errorf("empty option key in %q", value)
+	}
+	raw := imgproxyurl.Raw{OptionKey: parts[0]}
+	for _, part := range parts[1:] {
+		raw.Parameters = append(raw.Parameters, part)
+	}
+	*r = append(*r, raw)
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+)
+
+const (
+	testKey  = "e99bd6542067de7dac460558ecada3987dd2d18b066180eaa1c3abc66fb22e463d177ac8f64c93c44d0d78c35adcdda7e0b5f5a116b23ac3d1fa7a305d0727c4"
+	testSalt = "a997d51b78d28ba8c05f39b6e634a044b9551352b105f70a4c0fc4c0eca5982719a33527d0253810273bf4d8b747a261cd4898d3e46916cc57d1de8aac132870"
+	testUrl  = "https://example.com/Yysx5pZ_gcWJbVQEHSp37U6r3swrZgFAygnHmbFK2VE/h:200/rt:fill/w:200/bG9jYWw6Ly8vby90L290Uk8xamwzSVVWYS5qcGc.png"
+)
+
+// setenv sets an environment variable for the duration of the test.
+func setenv(t *testing.T, key string, value string) {
+	previous, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			_ = os.Setenv(key, previous)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	})
+}
+
+func testEnv(t *testing.T) {
+	noEnv(t)
+	setenv(t, "IMGPROXY_KEY", testKey)
+	setenv(t, "IMGPROXY_SALT", testSalt)
+}
+
+func noEnv(t *testing.T) {
+	for _, name := range []string{"KEY", "SALT", "SIGNATURE_SIZE", "SOURCE_URL_ENCRYPTION_KEY", "ENDPOINT"} {
+		setenv(t, "IMGPROXY_"+name, "")
+	}
+}
+
+func Test_run(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      func(*testing.T)
+		wantCode int
+		want     string
+	}{
+		{name: "sign", args: []string{"sign", "-endpoint", "https://example.com/", "-w", "200", "-h", "200", "-rt", "fill", "-format", "png", "local:///o/t/otRO1jl3IUVa.jpg"}, env: testEnv, want: testUrl + "\n"},
+		{name: "sign w/ flags instead of env", args: []string{"sign", "-key", testKey, "-salt", testSalt, "-endpoint", "https://example.com/", "-w", "200", "-h", "200", "-rt", "fill", "-format", "png", "local:///o/t/otRO1jl3IUVa.jpg"}, env: noEnv, want: testUrl + "\n"},
+		{name: "sign w/ key flag and salt from env", args: []string{"sign", "-key", testKey, "-endpoint", "https://example.com/", "-w", "200", "-h", "200", "-rt", "fill", "-format", "png", "local:///o/t/otRO1jl3IUVa.jpg"}, env: func(t *testing.T) {
+			noEnv(t)
+			setenv(t, "IMGPROXY_KEY", testSalt)
+			setenv(t, "IMGPROXY_SALT", testSalt)
+		}, want: testUrl + "\n"},
+		{name: "sign insecure", args: []string{"sign", "-plain", "-o", "raw:1:2:test", "-w", "0", "local:///a.jpg"}, env: noEnv, want: "/insecure/raw:1:2:test/w:0/plain/local%3A%2F%2F%2Fa.jpg\n"},
+		{name: "sign invalid option", args: []string{"sign", "-q", "500", "local:///a.jpg"}, env: noEnv, wantCode: 1},
+		{name: "verify", args: []string{"verify", testUrl}, env: testEnv, want: "OK\n"},
+		{name: "verify mismatch", args: []string{"verify", strings.Replace(testUrl, "h:200", "h:300", 1)}, env: testEnv, wantCode: 1},
+		{name: "verify w/o key", args: []string{"verify", testUrl}, env: noEnv, wantCode: 1},
+		{name: "verify w/ multiple keys", args: []string{"verify", testUrl}, env: func(t *testing.T) {
+			noEnv(t)
+			setenv(t, "IMGPROXY_KEY", testKey+","+testSalt)
+			setenv(t, "IMGPROXY_SALT", testSalt+","+testKey)
+		}, want: "OK\n"},
+		{name: "verify w/ a rotated key", args: []string{"verify", testUrl}, env: func(t *testing.T) {
+			noEnv(t)
+			setenv(t, "IMGPROXY_KEY", testSalt+","+testKey)
+			setenv(t, "IMGPROXY_SALT", testKey+","+testSalt)
+		}, want: "OK\n"},
+		{name: "half-configured env", args: []string{"verify", testUrl}, env: func(t *testing.T) {
+			noEnv(t)
+			setenv(t, "IMGPROXY_KEY", testKey)
+		}, wantCode: 1},
+		{name: "decode", args: []string{"decode", testUrl}, env: noEnv, want: "endpoint: https://example.com\nsource:   local:///o/t/otRO1jl3IUVa.jpg\nformat:   png\noptions:\n  h:200\n  rt:fill\n  w:200\n"},
+		{name: "explain", args: []string{"explain", testUrl}, env: testEnv, want: "signature: valid\nsource image \"local:///o/t/otRO1jl3IUVa.jpg\"\nh    height              200\nrt   resizing_type       fill\nw    width               200\nconverted to png\n"},
+		{name: "explain full names", args: []string{"explain", "/insecure/width:100/bG9jYWw6Ly8vYS5qcGc"}, env: noEnv, want: "signature: not checked (no key/salt)\nsource image \"local:///a.jpg\"\nw    width               100\n"},
+		{name: "no command", args: nil, env: noEnv, wantCode: 2},
+		{name: "unknown command", args: []string{"frobnicate"}, env: noEnv, wantCode: 2},
+		{name: "no url", args: []string{"decode"}, env: noEnv, wantCode: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			tt.env(t)
+			if code := run(tt.args, &stdout, &stderr); code != tt.wantCode {
+				t.Errorf("run() = %v, want %v (stderr: %s)", code, tt.wantCode, stderr.String())
+			}
+			if tt.wantCode == 0 && stdout.String() != tt.want {
+				t.Errorf("run() stdout = %q, want %q", stdout.String(), tt.want)
+			}
+		})
+	}
+}
+
+func Test_keygen(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"keygen"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() = %v, want 0 (stderr: %s)", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "IMGPROXY_KEY=") || !strings.HasPrefix(lines[1], "IMGPROXY_SALT=") {
+		t.Fatalf("run() stdout = %q", stdout.String())
+	}
+	for _, line := range lines {
+		if _, err := hex.DecodeString(line[strings.Index(line, "=")+1:]); err != nil {
+			t.Errorf("%q: %v", line, err)
+		}
+	}
+}
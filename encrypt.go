@@ -0,0 +1,60 @@
+package imgproxyurl
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/pkg/errors"
+)
+
+// encryptSourceUrl encrypts sourceUrl with AES-CBC the way imgproxy Pro expects it for the "enc/" source urls.
+// The IV is derived from the source url itself so the resulting url is stable for the same source (and cacheable).
+func encryptSourceUrl(key []byte, sourceUrl string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", errors.WithMessage(err, "encryption key")
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(sourceUrl))
+	iv := mac.Sum(nil)[:aes.BlockSize]
+
+	padding := aes.BlockSize - len(sourceUrl)%aes.BlockSize
+	data := append([]byte(sourceUrl), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	result := make([]byte, aes.BlockSize+len(data))
+	copy(result, iv)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(result[aes.BlockSize:], data)
+
+	return base64.RawURLEncoding.EncodeToString(result), nil
+}
+
+// DecryptSourceUrl decrypts a source url encrypted with imgproxy's "enc/" scheme.
+// encrypted is the base64-encoded part of the url without the "enc/" prefix and the extension.
+func DecryptSourceUrl(key []byte, encrypted string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", errors.WithMessage(err, "encryption key")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", errors.WithMessage(err, "base64")
+	}
+	if len(data) < 2*aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return "", errors.New("encrypted source url has invalid length")
+	}
+
+	iv, data := data[:aes.BlockSize], data[aes.BlockSize:]
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(data, data)
+
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > aes.BlockSize || !bytes.Equal(data[len(data)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return "", errors.New("encrypted source url has invalid padding")
+	}
+
+	return string(data[:len(data)-padding]), nil
+}
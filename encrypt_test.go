@@ -0,0 +1,78 @@
+package imgproxyurl
+
+import (
+	"strings"
+	"testing"
+)
+
+const testEncryptionKey = "1eb5b0e971ad7f45324c1bb15c947cb207c43152fa5c6c7f35c4f36e0c18e0f1"
+
+func TestUrl_String_encrypted(t *testing.T) {
+	u, err := New("s3://bucket/secret/path.jpg", Width{100}, Format{"webp"}, EncryptedSourceUrl{true}, EncryptionKey{testEncryptionKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := u.String()
+	if !strings.HasPrefix(s, "/insecure/w:100/enc/") || !strings.HasSuffix(s, ".webp") {
+		t.Errorf("String() = %v, want an encrypted source url", s)
+	}
+	if strings.Contains(s, "secret") {
+		t.Errorf("String() = %v contains the source url", s)
+	}
+	if s2 := u.String(); s2 != s {
+		t.Errorf("String() is not stable: %v != %v", s, s2)
+	}
+
+	parsed, err := Parse(s, EncryptionKey{testEncryptionKey})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if parsed.sourceUrl != u.sourceUrl || parsed.format != u.format || !parsed.encryptedSourceUrl {
+		t.Errorf("Parse() source = %v (format %v), want %v (format %v)", parsed.sourceUrl, parsed.format, u.sourceUrl, u.format)
+	}
+	if _, err := Parse(s); err == nil {
+		t.Errorf("Parse() w/o encryption key expected an error")
+	}
+}
+
+func TestDecryptSourceUrl(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	for _, sourceUrl := range []string{"", "a", "local:///0123456789abcdef", "https://example.com/some/long/path/to/an/image.jpg?with=query"} {
+		encrypted, err := encryptSourceUrl(key, sourceUrl)
+		if err != nil {
+			t.Fatalf("encryptSourceUrl() error = %v", err)
+		}
+		got, err := DecryptSourceUrl(key, encrypted)
+		if err != nil {
+			t.Fatalf("DecryptSourceUrl() error = %v", err)
+		}
+		if got != sourceUrl {
+			t.Errorf("DecryptSourceUrl() = %v, want %v", got, sourceUrl)
+		}
+	}
+
+	if _, err := DecryptSourceUrl(key, "AAAA"); err == nil {
+		t.Errorf("DecryptSourceUrl() expected an error for short input")
+	}
+	if _, err := DecryptSourceUrl([]byte("short"), "AAAA"); err == nil {
+		t.Errorf("DecryptSourceUrl() expected an error for invalid key")
+	}
+}
+
+func TestUrl_applyOptions_encryption(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+	}{
+		{name: "no key", options: []Option{EncryptedSourceUrl{true}}},
+		{name: "malformed key", options: []Option{EncryptedSourceUrl{true}, EncryptionKey{"zz"}}},
+		{name: "invalid key size", options: []Option{EncryptedSourceUrl{true}, EncryptionKeyRaw{[]byte("short")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New("local:///a.jpg", tt.options...); err == nil {
+				t.Errorf("New() expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package imgproxyurl
+
+import (
+	"github.com/pkg/errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultEnvPrefix is the prefix imgproxy uses for its environment variables.
+const DefaultEnvPrefix = "IMGPROXY_"
+
+// ConfigFromEnv reads the configuration from the same environment variables imgproxy uses and returns it as options:
+//
+// KEY, SALT: hex-encoded key and salt. Comma-separated lists are supported (as in imgproxy): they are returned
+// as a Keyring (see Keys) with the pairs identified by their 1-based positions, the first pair is used for signing.
+//
+// SIGNATURE_SIZE: number of signature bytes.
+//
+// SOURCE_URL_ENCRYPTION_KEY: hex-encoded key for the encrypted source urls.
+//
+// ENDPOINT: imgproxy endpoint. This one is not used by imgproxy itself.
+//
+// All names are prefixed with prefix, DefaultEnvPrefix is used when prefix is empty.
+func ConfigFromEnv(prefix string) ([]Option, error) {
+	return configFromEnv(prefix, os.LookupEnv)
+}
+
+// FromEnv returns a Url configured from the environment variables only (see ConfigFromEnv),
+// the global settings are not used. Use its WithOptions method to build the actual urls.
+func FromEnv(prefix string) (*Url, error) {
+	options, err := ConfigFromEnv(prefix)
+	if err != nil {
+		return nil, err
+	}
+	b, err := NewBuilder(options...)
+	if err != nil {
+		return nil, err
+	}
+	return b.New("")
+}
+
+func configFromEnv(prefix string, lookup func(string) (string, bool)) ([]Option, error) {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	get := func(name string) string {
+		value, _ := lookup(prefix + name)
+		return strings.TrimSpace(value)
+	}
+
+	var options []Option
+
+	keys, salts := splitList(get("KEY")), splitList(get("SALT"))
+	switch {
+	case len(keys) == 0 && len(salts) == 0:
+	case len(keys) == 0:
+		return nil, errors.Errorf("%sSALT is set but %sKEY is not", prefix, prefix)
+	case len(salts) == 0:
+		return nil, errors.Errorf("%sKEY is set but %sSALT is not", prefix, prefix)
+	case len(keys) != len(salts):
+		return nil, errors.Errorf("%sKEY has %d keys but %sSALT has %d salts", prefix, len(keys), prefix, len(salts))
+	default:
+		for i := range keys {
+			if keys[i] == "" || salts[i] == "" {
+				return nil, errors.Errorf("%sKEY/%sSALT pair %d is empty", prefix, prefix, i+1)
+			}
+		}
+		if len(keys) == 1 {
+			options = append(options, Key{keys[0]}, Salt{salts[0]})
+			break
+		}
+		pairs := make([]KeyPair, len(keys))
+		for i := range keys {
+			pair, err := NewKeyPair(strconv.Itoa(i+1), keys[i], salts[i])
+			if err != nil {
+				return nil, errors.WithMessagef(err, "%sKEY/%sSALT", prefix, prefix)
+			}
+			pairs[i] = pair
+		}
+		keyring, err := NewKeyring(pairs...)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, Keys{keyring})
+	}
+
+	if size := get("SIGNATURE_SIZE"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil {
+			return nil, errors.WithMessage(err, prefix+"SIGNATURE_SIZE")
+		}
+		options = append(options, SignatureSize{n})
+	}
+
+	if key := get("SOURCE_URL_ENCRYPTION_KEY"); key != "" {
+		options = append(options, EncryptionKey{key})
+	}
+
+	if endpoint := get("ENDPOINT"); endpoint != "" {
+		options = append(options, Endpoint{endpoint})
+	}
+
+	return options, nil
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	list := strings.Split(s, ",")
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+	return list
+}
@@ -0,0 +1,111 @@
+package imgproxyurl
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func Test_configFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		env     map[string]string
+		want    []Option
+		wantErr bool
+	}{
+		{name: "empty", env: map[string]string{}, want: nil},
+		{name: "full", env: map[string]string{
+			"IMGPROXY_KEY":                       "aa",
+			"IMGPROXY_SALT":                      "bb",
+			"IMGPROXY_SIGNATURE_SIZE":            "8",
+			"IMGPROXY_SOURCE_URL_ENCRYPTION_KEY": "cc",
+			"IMGPROXY_ENDPOINT":                  "https://example.com",
+		}, want: []Option{Key{"aa"}, Salt{"bb"}, SignatureSize{8}, EncryptionKey{"cc"}, Endpoint{"https://example.com"}}},
+		{name: "custom prefix", prefix: "IMG_", env: map[string]string{
+			"IMGPROXY_KEY": "aa",
+			"IMG_KEY":      "cc",
+			"IMG_SALT":     "dd",
+		}, want: []Option{Key{"cc"}, Salt{"dd"}}},
+		{name: "multiple keys", env: map[string]string{
+			"IMGPROXY_KEY":  "aa, cc",
+			"IMGPROXY_SALT": "bb,dd",
+		}, want: []Option{Keys{&Keyring{pairs: []KeyPair{
+			{ID: "1", Key: []byte{0xaa}, Salt: []byte{0xbb}},
+			{ID: "2", Key: []byte{0xcc}, Salt: []byte{0xdd}},
+		}}}}},
+		{name: "multiple keys, malformed", env: map[string]string{"IMGPROXY_KEY": "aa,zz", "IMGPROXY_SALT": "bb,dd"}, wantErr: true},
+		{name: "key w/o salt", env: map[string]string{"IMGPROXY_KEY": "aa"}, wantErr: true},
+		{name: "salt w/o key", env: map[string]string{"IMGPROXY_SALT": "aa"}, wantErr: true},
+		{name: "keys/salts count mismatch", env: map[string]string{"IMGPROXY_KEY": "aa,cc", "IMGPROXY_SALT": "bb"}, wantErr: true},
+		{name: "empty pair", env: map[string]string{"IMGPROXY_KEY": "aa,", "IMGPROXY_SALT": "bb,dd"}, wantErr: true},
+		{name: "malformed signature size", env: map[string]string{"IMGPROXY_SIGNATURE_SIZE": "eight"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := configFromEnv(tt.prefix, func(name string) (string, bool) {
+				value, ok := tt.env[name]
+				return value, ok
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("configFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("configFromEnv() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// setenv sets an environment variable for the duration of the test.
+func setenv(t *testing.T, key string, value string) {
+	previous, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			_ = os.Setenv(key, previous)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	})
+}
+
+func TestFromEnv(t *testing.T) {
+	setenv(t, "IMGPROXY_KEY", testKey+","+testSalt)
+	setenv(t, "IMGPROXY_SALT", testSalt+","+testKey)
+	setenv(t, "IMGPROXY_ENDPOINT", "https://example.com/")
+
+	base, err := FromEnv("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := base.WithOptions(SourceUrl{"local:///o/t/otRO1jl3IUVa.jpg"}, Width{200}, Height{200}, Format{"png"}, ResizingType{ResizingTypeFill})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u.String(), "https://example.com/Yysx5pZ_gcWJbVQEHSp37U6r3swrZgFAygnHmbFK2VE/h:200/rt:fill/w:200/bG9jYWw6Ly8vby90L290Uk8xamwzSVVWYS5qcGc.png"; got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+
+	setenv(t, "IMGPROXY_KEY", "not hex")
+	if _, err := FromEnv(""); err == nil {
+		t.Errorf("FromEnv() expected an error")
+	}
+}
+
+func TestFromEnv_noGlobals(t *testing.T) {
+	defer SetDefault(Default())
+
+	if err := SetKeySalt(testKey, testSalt); err != nil {
+		t.Fatal(err)
+	}
+	u, err := FromEnv("NOPE_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Signed() {
+		t.Errorf("FromEnv() used the global key and salt")
+	}
+}
@@ -1,5 +1,5 @@
 module github.com/penyaev/imgproxyurl
 
-go 1.15
+go 1.16
 
 require github.com/pkg/errors v0.9.1
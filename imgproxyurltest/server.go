@@ -0,0 +1,220 @@
+// Package imgproxyurltest provides a fake imgproxy server for integration tests.
+//
+// The server checks signatures, decodes the urls, resolves local:// sources from an fs.FS and records every request,
+// so tests can assert which images were requested with which options without running imgproxy.
+package imgproxyurltest
+
+import (
+	"bytes"
+	"github.com/penyaev/imgproxyurl"
+	"github.com/penyaev/imgproxyurl/process"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"  // register the decoder
+	_ "image/jpeg" // register the decoder
+	_ "image/png"  // register the decoder
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// Mode defines what the server responds with.
+type Mode int
+
+const (
+	// ModeSource responds with the source image bytes as they are.
+	ModeSource Mode = iota
+	// ModePlaceholder responds with a solid gray image of the size imgproxy would return (see Url.ResultSize).
+	// Formats the process package can't encode (webp, avif, etc.) are substituted with png,
+	// the Content-Type is still the one of the requested format.
+	ModePlaceholder
+	// ModeProcess responds with the source image processed by the process package.
+	// Unsupported formats are substituted with png the same way as in ModePlaceholder.
+	ModeProcess
+)
+
+// PlaceholderColor is the color of the placeholder images.
+var PlaceholderColor = color.NRGBA{R: 0xcc, G: 0xcc, B: 0xcc, A: 0xff}
+
+// Config configures a Server.
+type Config struct {
+	// Options configure key, salt, signature size, encryption key, etc. the same way they configure urls.
+	// When key and salt are set, the server rejects requests with invalid signatures.
+	Options []imgproxyurl.Option
+	// FS resolves local:// source urls: local:///a/b.jpg is read from a/b.jpg.
+	FS fs.FS
+	// Mode defines what the server responds with.
+	Mode Mode
+}
+
+// Request is a request recorded by the Server.
+type Request struct {
+	// Path is the requested path, starting with the signature.
+	Path string
+	// Url is the requested url parsed with the Server options.
+	Url *imgproxyurl.Url
+	// Status is the response status code.
+	Status int
+}
+
+// Server is a fake imgproxy server.
+type Server struct {
+	*httptest.Server
+
+	config  Config
+	builder *imgproxyurl.Builder
+	base    *imgproxyurl.Url
+
+	mu       sync.Mutex
+	requests []Request
+}
+
+// NewServer starts a Server. The caller should call Close when finished.
+func NewServer(config Config) (*Server, error) {
+	builder, err := imgproxyurl.NewBuilder(config.Options...)
+	if err != nil {
+		return nil, err
+	}
+	base, err := builder.New("")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{config: config, builder: builder, base: base}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s, nil
+}
+
+// Builder returns a Builder producing urls for the server: it has the server options and the server endpoint.
+func (s *Server) Builder() *imgproxyurl.Builder {
+	b, err := s.builder.WithOptions(imgproxyurl.Endpoint{Endpoint: s.URL})
+	if err != nil {
+		// the options have been applied successfully in NewServer already
+		panic(err)
+	}
+	return b
+}
+
+// Requests returns the requests recorded so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Reset forgets the recorded requests.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+func (s *Server) record(r Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	request := Request{Path: r.URL.EscapedPath()}
+	request.Status = s.serve(w, request.Path, &request)
+	s.record(request)
+}
+
+func (s *Server) serve(w http.ResponseWriter, path string, request *Request) int {
+	if s.base.Signed() {
+		if err := s.base.Verify(path); err != nil {
+			return respondError(w, http.StatusForbidden, "Invalid signature: "+err.Error())
+		}
+	}
+
+	u, err := s.builder.Parse(path)
+	if err != nil {
+		return respondError(w, http.StatusNotFound, "Invalid URL: "+err.Error())
+	}
+	request.Url = u
+
+	source, err := s.readSource(u.SourceUrl())
+	if err != nil {
+		return respondError(w, http.StatusNotFound, "Can't download source image: "+err.Error())
+	}
+
+	if s.config.Mode == ModeSource {
+		return respond(w, http.DetectContentType(source), source)
+	}
+
+	var result image.Image
+	img, sourceFormat, err := image.Decode(bytes.NewReader(source))
+	if err != nil {
+		return respondError(w, http.StatusUnprocessableEntity, "Can't decode source image: "+err.Error())
+	}
+	if s.config.Mode == ModePlaceholder {
+		width, height, err := u.ResultSize(img.Bounds().Dx(), img.Bounds().Dy())
+		if err != nil {
+			return respondError(w, http.StatusUnprocessableEntity, err.Error())
+		}
+		placeholder := image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(placeholder, placeholder.Bounds(), image.NewUniform(PlaceholderColor), image.Point{}, draw.Src)
+		result = placeholder
+	} else {
+		if result, err = process.Process(u, img); err != nil {
+			return respondError(w, http.StatusUnprocessableEntity, err.Error())
+		}
+	}
+
+	// the process package can't encode webp, avif, etc., such images are encoded as png
+	// but still served with the requested Content-Type
+	var contentType string
+	encodeUrl := u
+	if !encodable(u.Format()) {
+		contentType = imgproxyurl.FormatName(u.Format()).MimeType()
+		if encodeUrl, err = u.WithOptions(imgproxyurl.Format{Format: imgproxyurl.FormatPng}); err != nil {
+			return respondError(w, http.StatusUnprocessableEntity, err.Error())
+		}
+	}
+
+	var b bytes.Buffer
+	if err := process.Encode(&b, encodeUrl, result, sourceFormat); err != nil {
+		return respondError(w, http.StatusUnprocessableEntity, err.Error())
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(b.Bytes())
+	}
+	return respond(w, contentType, b.Bytes())
+}
+
+// encodable reports whether process.Encode supports the format. Empty format means the source image format,
+// which is always one of the supported ones since only these are decoded.
+func encodable(format string) bool {
+	switch strings.ToLower(format) {
+	case "", "jpg", "jpeg", "png", "gif":
+		return true
+	}
+	return false
+}
+
+func (s *Server) readSource(sourceUrl string) ([]byte, error) {
+	const prefix = "local:///"
+	if !strings.HasPrefix(sourceUrl, prefix) {
+		return nil, fs.ErrNotExist
+	}
+	if s.config.FS == nil {
+		return nil, fs.ErrNotExist
+	}
+	return fs.ReadFile(s.config.FS, strings.TrimPrefix(sourceUrl, prefix))
+}
+
+func respond(w http.ResponseWriter, contentType string, body []byte) int {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+	return http.StatusOK
+}
+
+func respondError(w http.ResponseWriter, status int, message string) int {
+	http.Error(w, message, status)
+	return status
+}
@@ -0,0 +1,206 @@
+package imgproxyurltest
+
+import (
+	"bytes"
+	"github.com/penyaev/imgproxyurl"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"testing"
+	"testing/fstest"
+)
+
+func testImage(t *testing.T, width int, height int) []byte {
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewNRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func get(t *testing.T, url string) (int, []byte) {
+	resp, body := getResponse(t, url)
+	return resp.StatusCode, body
+}
+
+func getResponse(t *testing.T, url string) (*http.Response, []byte) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, body
+}
+
+func TestServer(t *testing.T) {
+	source := testImage(t, 400, 300)
+	fsys := fstest.MapFS{"images/a.png": {Data: source}}
+
+	tests := []struct {
+		name        string
+		mode        Mode
+		source      string
+		options     []imgproxyurl.Option
+		status      int
+		contentType string
+		body        []byte
+		resultSize  image.Point
+	}{
+		{
+			name:   "source",
+			mode:   ModeSource,
+			status: http.StatusOK,
+			body:   source,
+		},
+		{
+			name:       "placeholder",
+			mode:       ModePlaceholder,
+			options:    []imgproxyurl.Option{imgproxyurl.Width{W: 200}},
+			status:     http.StatusOK,
+			resultSize: image.Pt(200, 150),
+		},
+		{
+			name:       "process",
+			mode:       ModeProcess,
+			options:    []imgproxyurl.Option{imgproxyurl.Width{W: 100}, imgproxyurl.Height{H: 100}, imgproxyurl.ResizingType{ResizingType: imgproxyurl.ResizingTypeFill}},
+			status:     http.StatusOK,
+			resultSize: image.Pt(100, 100),
+		},
+		{
+			name:        "process as webp",
+			mode:        ModeProcess,
+			options:     []imgproxyurl.Option{imgproxyurl.Width{W: 100}, imgproxyurl.Format{Format: imgproxyurl.FormatWebp}},
+			status:      http.StatusOK,
+			contentType: "image/webp",
+			resultSize:  image.Pt(100, 75),
+		},
+		{
+			name:        "placeholder as avif",
+			mode:        ModePlaceholder,
+			options:     []imgproxyurl.Option{imgproxyurl.Height{H: 150}, imgproxyurl.Format{Format: imgproxyurl.FormatAvif}},
+			status:      http.StatusOK,
+			contentType: "image/avif",
+			resultSize:  image.Pt(200, 150),
+		},
+		{
+			name:       "plain source url",
+			mode:       ModePlaceholder,
+			options:    []imgproxyurl.Option{imgproxyurl.PlainSourceUrl{Plain: true}, imgproxyurl.Height{H: 30}},
+			status:     http.StatusOK,
+			resultSize: image.Pt(40, 30),
+		},
+		{
+			name:   "missing source",
+			mode:   ModeSource,
+			source: "local:///images/b.png",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "remote source",
+			mode:   ModeSource,
+			source: "https://example.com/a.png",
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := NewServer(Config{
+				Options: []imgproxyurl.Option{imgproxyurl.Key{Key: "1234"}, imgproxyurl.Salt{Salt: "5678"}},
+				FS:      fsys,
+				Mode:    test.mode,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			sourceUrl := test.source
+			if sourceUrl == "" {
+				sourceUrl = "local:///images/a.png"
+			}
+			u, err := s.Builder().New(sourceUrl, test.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, body := getResponse(t, u.String())
+			if resp.StatusCode != test.status {
+				t.Fatalf("got status %d, expected %d: %s", resp.StatusCode, test.status, body)
+			}
+			if contentType := resp.Header.Get("Content-Type"); test.contentType != "" && contentType != test.contentType {
+				t.Errorf("got Content-Type %q, expected %q", contentType, test.contentType)
+			}
+			if test.body != nil && !bytes.Equal(body, test.body) {
+				t.Errorf("unexpected body")
+			}
+			if test.resultSize != (image.Point{}) {
+				config, _, err := image.DecodeConfig(bytes.NewReader(body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if size := image.Pt(config.Width, config.Height); size != test.resultSize {
+					t.Errorf("got size %v, expected %v", size, test.resultSize)
+				}
+			}
+
+			requests := s.Requests()
+			if len(requests) != 1 {
+				t.Fatalf("got %d requests, expected 1", len(requests))
+			}
+			if requests[0].Status != test.status {
+				t.Errorf("recorded status %d, expected %d", requests[0].Status, test.status)
+			}
+			if got, expected := requests[0].Url.Options(), u.Options(); !equal(got, expected) {
+				t.Errorf("recorded options %v, expected %v", got, expected)
+			}
+		})
+	}
+}
+
+func TestServerSignature(t *testing.T) {
+	s, err := NewServer(Config{
+		Options: []imgproxyurl.Option{imgproxyurl.Key{Key: "1234"}, imgproxyurl.Salt{Salt: "5678"}},
+		FS:      fstest.MapFS{"a.png": {Data: testImage(t, 10, 10)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	u, err := imgproxyurl.New("local:///a.png", imgproxyurl.Endpoint{Endpoint: s.URL}, imgproxyurl.Key{Key: "abcd"}, imgproxyurl.Salt{Salt: "5678"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := get(t, u.String()); status != http.StatusForbidden {
+		t.Errorf("got status %d for a wrong key, expected %d", status, http.StatusForbidden)
+	}
+
+	s.Reset()
+	u, err = s.Builder().New("local:///a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := get(t, u.String()); status != http.StatusOK {
+		t.Errorf("got status %d, expected %d", status, http.StatusOK)
+	}
+	if requests := s.Requests(); len(requests) != 1 {
+		t.Errorf("got %d requests after reset, expected 1", len(requests))
+	}
+}
+
+func equal(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
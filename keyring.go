@@ -0,0 +1,176 @@
+package imgproxyurl
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/pkg/errors"
+)
+
+// generatedKeySize is the size of the generated keys and salts in bytes, the same as imgproxy docs suggest.
+const generatedKeySize = 64
+
+// KeyPair is a key/salt pair identified by ID.
+type KeyPair struct {
+	ID   string
+	Key  []byte
+	Salt []byte
+}
+
+// NewKeyPair creates a KeyPair from the hex-encoded key and salt.
+func NewKeyPair(id string, key string, salt string) (KeyPair, error) {
+	keyBytes, err := hex.DecodeString(key)
+	if err != nil {
+		return KeyPair{}, errors.WithMessagef(err, "key %q: hexdecode", id)
+	}
+	saltBytes, err := hex.DecodeString(salt)
+	if err != nil {
+		return KeyPair{}, errors.WithMessagef(err, "salt %q: hexdecode", id)
+	}
+	return KeyPair{ID: id, Key: keyBytes, Salt: saltBytes}, nil
+}
+
+// GenerateKeyPair creates a KeyPair with a cryptographically random key and salt.
+func GenerateKeyPair(id string) (KeyPair, error) {
+	pair := KeyPair{ID: id, Key: make([]byte, generatedKeySize), Salt: make([]byte, generatedKeySize)}
+	if _, err := rand.Read(pair.Key); err != nil {
+		return KeyPair{}, errors.WithMessage(err, "generate key")
+	}
+	if _, err := rand.Read(pair.Salt); err != nil {
+		return KeyPair{}, errors.WithMessage(err, "generate salt")
+	}
+	return pair, nil
+}
+
+// GenerateKeySalt returns a cryptographically random hex-encoded key and salt ready for IMGPROXY_KEY and IMGPROXY_SALT.
+func GenerateKeySalt() (string, string, error) {
+	pair, err := GenerateKeyPair("")
+	if err != nil {
+		return "", "", err
+	}
+	return pair.KeyHex(), pair.SaltHex(), nil
+}
+
+// KeyHex returns the hex-encoded key.
+func (p KeyPair) KeyHex() string {
+	return hex.EncodeToString(p.Key)
+}
+
+// SaltHex returns the hex-encoded salt.
+func (p KeyPair) SaltHex() string {
+	return hex.EncodeToString(p.Salt)
+}
+
+// Keyring is an ordered set of key/salt pairs used for key rotation: urls are signed with the active pair,
+// while verification accepts a signature made with any of the pairs (as imgproxy does with several
+// comma-separated IMGPROXY_KEY/IMGPROXY_SALT pairs).
+//
+// A Keyring is immutable and safe for concurrent use. Use it with the Keys option.
+type Keyring struct {
+	pairs  []KeyPair
+	active int
+}
+
+// NewKeyring creates a Keyring with the given pairs. The first pair is the active one.
+// The IDs must be unique, keys and salts must not be empty.
+func NewKeyring(pairs ...KeyPair) (*Keyring, error) {
+	if len(pairs) == 0 {
+		return nil, errors.New("keyring: no key pairs")
+	}
+	seen := make(map[string]bool, len(pairs))
+	for _, pair := range pairs {
+		if seen[pair.ID] {
+			return nil, errors.Errorf("keyring: duplicate key pair id %q", pair.ID)
+		}
+		seen[pair.ID] = true
+		if len(pair.Key) == 0 || len(pair.Salt) == 0 {
+			return nil, errors.Errorf("keyring: key pair %q has an empty key or salt", pair.ID)
+		}
+	}
+	return &Keyring{pairs: append([]KeyPair(nil), pairs...)}, nil
+}
+
+// Active returns the pair used for signing. It returns false for an empty Keyring
+// (e.g. a zero value not created with NewKeyring).
+func (k *Keyring) Active() (KeyPair, bool) {
+	if k == nil || k.active >= len(k.pairs) {
+		return KeyPair{}, false
+	}
+	return k.pairs[k.active], true
+}
+
+// Pairs returns all the pairs in order.
+func (k *Keyring) Pairs() []KeyPair {
+	return append([]KeyPair(nil), k.pairs...)
+}
+
+// Pair returns the pair with the given ID.
+func (k *Keyring) Pair(id string) (KeyPair, bool) {
+	if i := k.index(id); i >= 0 {
+		return k.pairs[i], true
+	}
+	return KeyPair{}, false
+}
+
+// WithActive returns a copy of the Keyring with the pair with the given ID made active.
+func (k *Keyring) WithActive(id string) (*Keyring, error) {
+	i := k.index(id)
+	if i < 0 {
+		return nil, errors.Errorf("keyring: no key pair with id %q", id)
+	}
+	return &Keyring{pairs: k.pairs, active: i}, nil
+}
+
+// WithPair returns a copy of the Keyring with the pair added to the end. The active pair stays the same.
+func (k *Keyring) WithPair(pair KeyPair) (*Keyring, error) {
+	result, err := NewKeyring(append(k.Pairs(), pair)...)
+	if err != nil {
+		return nil, err
+	}
+	result.active = k.active
+	return result, nil
+}
+
+// Without returns a copy of the Keyring without the pair with the given ID. The active pair can't be removed.
+func (k *Keyring) Without(id string) (*Keyring, error) {
+	i := k.index(id)
+	if i < 0 {
+		return nil, errors.Errorf("keyring: no key pair with id %q", id)
+	}
+	if i == k.active {
+		return nil, errors.Errorf("keyring: key pair %q is active", id)
+	}
+	pairs := append(k.Pairs()[:i], k.pairs[i+1:]...)
+	active := k.active
+	if i < active {
+		active--
+	}
+	return &Keyring{pairs: pairs, active: active}, nil
+}
+
+// Verify checks that rawUrl is signed with any of the pairs and returns the ID of the matching pair.
+// signatureSize is the number of signature bytes to compare, 0 means full-size signatures (see Verify).
+func (k *Keyring) Verify(rawUrl string, signatureSize int) (string, error) {
+	return k.verify(rawUrl, "", signatureSize)
+}
+
+func (k *Keyring) verify(rawUrl string, endpoint string, signatureSize int) (string, error) {
+	for _, pair := range k.pairs {
+		err := verify(rawUrl, endpoint, pair.Key, pair.Salt, signatureSize)
+		if err == nil {
+			return pair.ID, nil
+		}
+		if !errors.Is(err, ErrSignatureMismatch) {
+			return "", err
+		}
+	}
+	return "", ErrSignatureMismatch
+}
+
+func (k *Keyring) index(id string) int {
+	for i, pair := range k.pairs {
+		if pair.ID == id {
+			return i
+		}
+	}
+	return -1
+}
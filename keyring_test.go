@@ -0,0 +1,145 @@
+package imgproxyurl
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func testKeyring(t *testing.T) *Keyring {
+	old, err := NewKeyPair("old", testSalt, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := NewKeyPair("current", testKey, testSalt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := NewKeyring(current, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestNewKeyring(t *testing.T) {
+	pair := KeyPair{ID: "a", Key: []byte{1}, Salt: []byte{2}}
+	tests := []struct {
+		name    string
+		pairs   []KeyPair
+		wantErr bool
+	}{
+		{name: "valid", pairs: []KeyPair{pair, {ID: "b", Key: []byte{3}, Salt: []byte{4}}}},
+		{name: "empty", pairs: nil, wantErr: true},
+		{name: "duplicate id", pairs: []KeyPair{pair, pair}, wantErr: true},
+		{name: "empty key", pairs: []KeyPair{{ID: "a", Salt: []byte{2}}}, wantErr: true},
+		{name: "empty salt", pairs: []KeyPair{{ID: "a", Key: []byte{1}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKeyring(tt.pairs...); (err != nil) != tt.wantErr {
+				t.Errorf("NewKeyring() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	k := testKeyring(t)
+	if got, ok := k.Active(); !ok || got.ID != "current" {
+		t.Fatalf("Active() = %v, want current", got.ID)
+	}
+
+	current, err := New("local:///a.jpg", Width{100}, Keys{k})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := New("local:///a.jpg", Width{100}, Key{testKey}, Salt{testSalt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.String() != plain.String() {
+		t.Errorf("String() = %v, want %v", current.String(), plain.String())
+	}
+
+	old, err := New("local:///a.jpg", Width{100}, Key{testSalt}, Salt{testKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := current.Verify(old.String()); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if id, err := k.Verify(old.String(), 0); err != nil || id != "old" {
+		t.Errorf("Keyring.Verify() = %v, %v, want old", id, err)
+	}
+
+	other, err := New("local:///a.jpg", Width{100}, Key{testKey}, Salt{testKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := current.Verify(other.String()); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("Verify() error = %v, wantErr %v", err, ErrSignatureMismatch)
+	}
+	if _, err := k.Verify("/insecure/bG9jYWw6Ly8vYS5qcGc", 0); !errors.Is(err, ErrInsecureSignature) {
+		t.Errorf("Keyring.Verify() error = %v, wantErr %v", err, ErrInsecureSignature)
+	}
+
+	// promote the old pair and drop the current one
+	rotated, err := k.WithActive("old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated, err = rotated.Without("current"); err != nil {
+		t.Fatal(err)
+	}
+	u, err := current.WithOptions(Keys{rotated})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.String() != old.String() {
+		t.Errorf("String() = %v, want %v", u.String(), old.String())
+	}
+	if err := u.Verify(plain.String()); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("Verify() error = %v, wantErr %v", err, ErrSignatureMismatch)
+	}
+	if _, err := rotated.Without("old"); err == nil {
+		t.Errorf("Without() removed the active pair")
+	}
+
+	// Key and Salt replace the keyring
+	u, err = current.WithOptions(Key{testKey}, Salt{testKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Verify(old.String()); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("Verify() error = %v, wantErr %v", err, ErrSignatureMismatch)
+	}
+}
+
+func TestKeyring_Empty(t *testing.T) {
+	if _, ok := (&Keyring{}).Active(); ok {
+		t.Errorf("Active() of an empty keyring returned a pair")
+	}
+	if _, err := New("local:///a.jpg", Keys{&Keyring{}}); err == nil {
+		t.Errorf("New() expected an error for an empty keyring")
+	}
+}
+
+func TestGenerateKeySalt(t *testing.T) {
+	key, salt, err := GenerateKeySalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{key, salt} {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) != generatedKeySize {
+			t.Errorf("got %d bytes, want %d", len(b), generatedKeySize)
+		}
+	}
+	if key == salt {
+		t.Errorf("key and salt are the same")
+	}
+}
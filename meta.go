@@ -0,0 +1,153 @@
+package imgproxyurl
+
+import (
+	"strings"
+)
+
+// resizeKeys are the keys of the options the resize meta-option consists of, in the order of its arguments.
+// The size meta-option has the same arguments except the resizing type.
+var resizeKeys = []string{ResizingType{}.Key(), Width{}.Key(), Height{}.Key(), Enlarge{}.Key(), Extend{}.Key()}
+
+// adjustKeys are the keys of the options the adjust meta-option consists of, in the order of its arguments.
+var adjustKeys = []string{Brightness{}.Key(), Contrast{}.Key(), Saturation{}.Key()}
+
+// metaKeys returns the keys of the options the meta-option with the given key consists of, in the order of its arguments,
+// or nil if key is not a meta-option.
+func metaKeys(key string) []string {
+	switch key {
+	case Resize{}.Key(), "resize":
+		return resizeKeys
+	case Size{}.Key(), "size":
+		return resizeKeys[1:]
+	case Adjust{}.Key(), "adjust":
+		return adjustKeys
+	}
+	return nil
+}
+
+// metaArguments splits the value of a meta-option into the arguments of its options.
+// The last option takes the rest of the arguments (e.g. extend itself and the gravity).
+func metaArguments(keys []string, value string) []string {
+	return strings.SplitN(value, ":", len(keys))
+}
+
+// setOption sets a processing option. The resize, size and adjust meta-options are split into the options they consist of,
+// so that these can be overridden separately afterwards.
+func (o processingOptions) setOption(key string, value string) processingOptions {
+	keys := metaKeys(key)
+	if keys == nil {
+		return o.set(key, value)
+	}
+	for i, argument := range metaArguments(keys, value) {
+		// empty arguments don't change the corresponding options
+		if argument != "" {
+			o = o.set(keys[i], argument)
+		}
+	}
+	return o
+}
+
+// metaArgument returns the argument of a meta-option kept as is (in parsed urls) for the option with the given key.
+// Empty arguments don't set the option.
+func (o processingOption) metaArgument(key string) (string, bool) {
+	keys := metaKeys(o.key)
+	arguments := metaArguments(keys, o.value)
+	for i, k := range keys {
+		if k == key && i < len(arguments) && arguments[i] != "" {
+			return arguments[i], true
+		}
+	}
+	return "", false
+}
+
+// withoutMetaArgument returns the meta-option with the argument for the option with the given key emptied,
+// so that the option can be overridden without rewriting the rest of the meta-option.
+func (o processingOption) withoutMetaArgument(key string) processingOption {
+	keys := metaKeys(o.key)
+	arguments := metaArguments(keys, o.value)
+	for i, k := range keys {
+		if k == key && i < len(arguments) {
+			arguments[i] = ""
+		}
+	}
+	for len(arguments) > 0 && arguments[len(arguments)-1] == "" {
+		arguments = arguments[:len(arguments)-1]
+	}
+	o.value = strings.Join(arguments, ":")
+	return o
+}
+
+// compacted folds the resizing type, width, height, enlarge and extend options into a single resize
+// (or size, if there's no resizing type) meta-option, and the brightness, contrast and saturation options
+// into a single adjust meta-option. A meta-option is placed where the first of its options was.
+//
+// With OptionsOrderInsertion the options are not folded across presets: moving an option before a preset
+// would let the preset override it.
+func (o processingOptions) compacted(order OptionsOrderName) processingOptions {
+	if order != OptionsOrderInsertion {
+		return o.compactedRun()
+	}
+	var result processingOptions
+	start := 0
+	for i, option := range o {
+		if option.key == (Presets{}).Key() {
+			result = append(append(result, o[start:i].compactedRun()...), option)
+			start = i + 1
+		}
+	}
+	return append(result, o[start:].compactedRun()...)
+}
+
+// compactedRun folds the options regardless of their order (see compacted).
+func (o processingOptions) compactedRun() processingOptions {
+	o = o.folded(resizeKeys, func(values map[string]string) (string, []string) {
+		if _, ok := values[ResizingType{}.Key()]; !ok {
+			return Size{}.Key(), resizeKeys[1:]
+		}
+		return Resize{}.Key(), resizeKeys
+	})
+	return o.folded(adjustKeys, func(map[string]string) (string, []string) {
+		return Adjust{}.Key(), adjustKeys
+	})
+}
+
+// folded folds the options with the given keys into a single meta-option. meta returns the key of the meta-option
+// and the keys of its arguments for the values found.
+func (o processingOptions) folded(foldKeys []string, meta func(values map[string]string) (string, []string)) processingOptions {
+	values := make(map[string]string, len(foldKeys))
+	first := -1
+	for i, option := range o {
+		for _, key := range foldKeys {
+			if option.key == key {
+				values[key] = option.value
+				if first < 0 {
+					first = i
+				}
+			}
+		}
+	}
+	if len(values) < 2 {
+		// a meta-option wouldn't make the url shorter
+		return o
+	}
+
+	key, keys := meta(values)
+	arguments := make([]string, len(keys))
+	for i, k := range keys {
+		arguments[i] = values[k]
+	}
+	for len(arguments) > 0 && arguments[len(arguments)-1] == "" {
+		arguments = arguments[:len(arguments)-1]
+	}
+
+	result := make(processingOptions, 0, len(o)-len(values)+1)
+	for i, option := range o {
+		if i == first {
+			result = append(result, processingOption{key: key, value: strings.Join(arguments, ":")})
+		}
+		if _, ok := values[option.key]; !ok {
+			result = append(result, option)
+		}
+	}
+	return result
+}
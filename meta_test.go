@@ -0,0 +1,122 @@
+package imgproxyurl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUrl_String_meta(t *testing.T) {
+	extend := &Extend{Extend: true, Gravity: &Gravity{Type: GravityTypeNorth}}
+	tests := []struct {
+		name    string
+		options []Option
+		want    string
+	}{
+		{name: "resize", options: []Option{Resize{ResizingType: ResizingTypeFill, Width: 200, Height: 100}},
+			want: "/insecure/el:false/h:100/rt:fill/w:200/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "resize compacted", options: []Option{Compact{true}, Resize{ResizingType: ResizingTypeFill, Width: 200, Height: 100, Enlarge: true, Extend: extend}},
+			want: "/insecure/rs:fill:200:100:true:true:no/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "size compacted", options: []Option{Compact{true}, Size{Width: 200, Height: 100}},
+			want: "/insecure/s:200:100:false/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "separate options compacted", options: []Option{Compact{true}, Quality{80}, Height{100}, ResizingType{ResizingTypeFit}},
+			want: "/insecure/q:80/rs:fit::100/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "separate options compacted w/o resizing type", options: []Option{Compact{true}, Width{100}, Extend{Extend: true}},
+			want: "/insecure/s:100:::true/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "single option is not compacted", options: []Option{Compact{true}, Width{100}},
+			want: "/insecure/w:100/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "overriding after resize", options: []Option{Compact{true}, Resize{ResizingType: ResizingTypeFill, Width: 200, Height: 100}, Width{300}},
+			want: "/insecure/rs:fill:300:100:false/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "insertion order", options: []Option{Compact{true}, OptionsOrder{OptionsOrderInsertion}, Quality{80}, Width{300}, Blur{1}, Height{100}},
+			want: "/insecure/q:80/s:300:100/bl:1/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "insertion order w/ a preset", options: []Option{Compact{true}, OptionsOrder{OptionsOrderInsertion}, Width{100}, Presets{[]string{"p"}}, Height{200}},
+			want: "/insecure/w:100/pr:p/h:200/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "insertion order w/ a preset after the options", options: []Option{Compact{true}, OptionsOrder{OptionsOrderInsertion}, Width{100}, Height{200}, Presets{[]string{"p"}}},
+			want: "/insecure/s:100:200/pr:p/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "adjust", options: []Option{Adjust{Brightness: &Brightness{10}, Saturation: &Saturation{1.2}}},
+			want: "/insecure/br:10/sa:1.2/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "adjust compacted", options: []Option{Compact{true}, Adjust{Brightness: &Brightness{10}, Saturation: &Saturation{1.2}}, Contrast{0.8}},
+			want: "/insecure/a:10:0.8:1.2/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "adjust and resize compacted", options: []Option{Compact{true}, Contrast{0.8}, Width{100}, Brightness{-10}, Height{50}},
+			want: "/insecure/a:-10:0.8/s:100:50/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "raw resize", options: []Option{Raw{OptionKey: "rs", Parameters: []interface{}{"fit", "", 100}}},
+			want: "/insecure/h:100/rt:fit/bG9jYWw6Ly8vYS5qcGc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := New("local:///a.jpg", tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := u.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUrl_WithOptions_meta(t *testing.T) {
+	u, err := New("local:///a.jpg", Compact{true}, Size{Width: 200, Height: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u2, err := u.WithOptions(Width{400}, ResizingType{ResizingTypeFill})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u2.Options(), []string{"rs:fill:400:100:false"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Options() = %v, want %v", got, want)
+	}
+	if got, want := u.Options(), []string{"s:200:100:false"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Options() of the original url = %v, want %v", got, want)
+	}
+}
+
+func TestParse_meta(t *testing.T) {
+	tests := []struct {
+		name   string
+		rawUrl string
+		// want maps option keys to their expected values
+		want map[string]string
+	}{
+		{name: "resize", rawUrl: "/insecure/rs:fill:200::1:1:so/bG9jYWw6Ly8vYS5qcGc",
+			want: map[string]string{"rt": "fill", "w": "200", "el": "1", "ex": "1:so"}},
+		{name: "size", rawUrl: "/insecure/q:80/s:100:50/bG9jYWw6Ly8vYS5qcGc", want: map[string]string{"w": "100", "h": "50"}},
+		{name: "adjust", rawUrl: "/insecure/a:10::1.5/bG9jYWw6Ly8vYS5qcGc", want: map[string]string{"br": "10", "sa": "1.5"}},
+		{name: "no meta-options", rawUrl: "/insecure/w:100/h:50/bG9jYWw6Ly8vYS5qcGc", want: map[string]string{"w": "100", "h": "50"}},
+		{name: "separate segments after resize", rawUrl: "/insecure/rs:fill:100:100/q:80/el:1/bG9jYWw6Ly8vYS5qcGc",
+			want: map[string]string{"w": "100", "el": "1"}},
+		{name: "full name", rawUrl: "/insecure/resize:fill:100:100/bG9jYWw6Ly8vYS5qcGc", want: map[string]string{"rt": "fill", "h": "100"}},
+		{name: "overridden argument", rawUrl: "/insecure/rs:fill:100:100:0/w:50/bG9jYWw6Ly8vYS5qcGc",
+			want: map[string]string{"w": "50", "h": "100", "el": "0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := Parse(tt.rawUrl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := u.String(); got != tt.rawUrl {
+				t.Errorf("String() = %v, want %v", got, tt.rawUrl)
+			}
+			for key, want := range tt.want {
+				if got, ok := u.Option(key); !ok || got != want {
+					t.Errorf("Option(%q) = %v, %v, want %v", key, got, ok, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParse_metaOverride(t *testing.T) {
+	u, err := Parse("/insecure/rs:fill:200::1:1:so/bG9jYWw6Ly8vYS5qcGc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u2, err := u.WithOptions(Width{300}, Extend{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u2.Options(), []string{"rs:fill:::1", "w:300", "ex:false"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Options() = %v, want %v", got, want)
+	}
+}
@@ -1,7 +1,10 @@
 package imgproxyurl
 
 import (
+	"encoding/base64"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -20,6 +23,48 @@ func format(key string, arguments ...interface{}) string {
 	return strings.Join(ss, ":")
 }
 
+// formatTrimmed is format with the trailing empty arguments omitted, so that unset optional arguments
+// don't appear in the url.
+func formatTrimmed(key string, arguments ...interface{}) string {
+	var ss []string
+	for _, argument := range arguments {
+		ss = append(ss, fmt.Sprint(argument))
+	}
+	for len(ss) > 0 && ss[len(ss)-1] == "" {
+		ss = ss[:len(ss)-1]
+	}
+	return strings.Join(ss, ":")
+}
+
+// Bool returns a pointer to b, for the optional boolean arguments (e.g. JpegOptions.Progressive).
+func Bool(b bool) *bool {
+	return &b
+}
+
+// Int returns a pointer to i, for the optional integer arguments (e.g. JpegOptions.QuantTable).
+func Int(i int) *int {
+	return &i
+}
+
+// optionalArgument returns the argument pointed to by p (a *bool, an *int or a *Color): an empty argument when p is nil.
+func optionalArgument(p interface{}) interface{} {
+	switch v := p.(type) {
+	case *bool:
+		if v != nil {
+			return *v
+		}
+	case *int:
+		if v != nil {
+			return *v
+		}
+	case *Color:
+		if v != nil {
+			return *v
+		}
+	}
+	return ""
+}
+
 // Width defines the width of the resulting image.
 // When set to 0, imgproxy will calculate the resulting width using the defined height and source aspect ratio.
 type Width struct {
@@ -32,6 +77,12 @@ func (Width) Key() string {
 func (o Width) String() string {
 	return format(o.Key(), o.W)
 }
+func (o Width) Validate() error {
+	if o.W < 0 {
+		return optionError(o.Key(), o.W, "must not be negative")
+	}
+	return nil
+}
 
 // Height defines the height of the resulting image.
 // When set to 0, imgproxy will calculate resulting height using the defined width and source aspect ratio.
@@ -45,6 +96,12 @@ func (Height) Key() string {
 func (o Height) String() string {
 	return format(o.Key(), o.H)
 }
+func (o Height) Validate() error {
+	if o.H < 0 {
+		return optionError(o.Key(), o.H, "must not be negative")
+	}
+	return nil
+}
 
 //ResizingTypeName defines how imgproxy will resize the source image.
 type ResizingTypeName string
@@ -54,6 +111,10 @@ const (
 	ResizingTypeFit ResizingTypeName = "fit"
 	//ResizingTypeFill resizes the image while keeping aspect ratio to fill given size and cropping projecting parts
 	ResizingTypeFill ResizingTypeName = "fill"
+	//ResizingTypeFillDown is the same as ResizingTypeFill, but if the resized image is smaller than the requested size, imgproxy will crop the result to keep the requested aspect ratio
+	ResizingTypeFillDown ResizingTypeName = "fill-down"
+	//ResizingTypeForce resizes the image without keeping the source aspect ratio
+	ResizingTypeForce ResizingTypeName = "force"
 	//if both source and resulting dimensions have the same orientation (portrait or landscape), imgproxy will use ResizingTypeFill. Otherwise, it will use ResizingTypeFit
 	ResizingTypeAuto ResizingTypeName = "auto"
 )
@@ -69,6 +130,13 @@ func (ResizingType) Key() string {
 func (o ResizingType) String() string {
 	return format(o.Key(), o.ResizingType)
 }
+func (o ResizingType) Validate() error {
+	switch o.ResizingType {
+	case ResizingTypeFit, ResizingTypeFill, ResizingTypeFillDown, ResizingTypeForce, ResizingTypeAuto:
+		return nil
+	}
+	return optionError(o.Key(), o.ResizingType, "unknown resizing type")
+}
 
 type ResizingAlgorithmName string
 
@@ -91,6 +159,13 @@ func (ResizingAlgorithm) Key() string {
 func (o ResizingAlgorithm) String() string {
 	return format(o.Key(), o.ResizingAlgorithm)
 }
+func (o ResizingAlgorithm) Validate() error {
+	switch o.ResizingAlgorithm {
+	case ResizingAlgorithmNearest, ResizingAlgorithmLinear, ResizingAlgorithmCubic, ResizingAlgorithmLanczos2, ResizingAlgorithmLanczos3:
+		return nil
+	}
+	return optionError(o.Key(), o.ResizingAlgorithm, "unknown resizing algorithm")
+}
 
 // When set, imgproxy will multiply the image dimensions according to this factor for HiDPI (Retina) devices.
 // The value must be greater than 0.
@@ -104,6 +179,12 @@ func (Dpr) Key() string {
 func (o Dpr) String() string {
 	return format(o.Key(), o.Dpr)
 }
+func (o Dpr) Validate() error {
+	if o.Dpr <= 0 {
+		return optionError(o.Key(), o.Dpr, "must be greater than 0")
+	}
+	return nil
+}
 
 // When set, imgproxy will enlarge the image if it is smaller than the given size.
 type Enlarge struct {
@@ -133,6 +214,82 @@ func (o Extend) String() string {
 	}
 	return format(o.Key(), arguments...)
 }
+func (o Extend) Validate() error {
+	if o.Gravity == nil {
+		return nil
+	}
+	if o.Gravity.Type == GravityTypeSmart {
+		return optionError(o.Key(), o.Gravity.Type, "smart gravity is not supported")
+	}
+	return o.Gravity.Validate()
+}
+
+//Resize is a meta-option that defines the resizing type, width, height, enlarge, and extend all at once.
+//
+//It is stored as the separate options, so setting e.g. Width afterwards overrides the width set by Resize.
+//Use Compact to get the meta-option in the url.
+type Resize struct {
+	ResizingType ResizingTypeName
+	Width        int
+	Height       int
+	Enlarge      bool
+	Extend       *Extend
+}
+
+func (Resize) Key() string {
+	return "rs"
+}
+func (o Resize) String() string {
+	var arguments = []interface{}{o.ResizingType, o.Width, o.Height, o.Enlarge}
+	if o.Extend != nil {
+		arguments = append(arguments, o.Extend)
+	}
+	return format(o.Key(), arguments...)
+}
+
+func (o Resize) Validate() error {
+	if o.ResizingType != "" {
+		if err := (ResizingType{o.ResizingType}).Validate(); err != nil {
+			return err
+		}
+	}
+	return (Size{Width: o.Width, Height: o.Height, Enlarge: o.Enlarge, Extend: o.Extend}).Validate()
+}
+
+//Size is a meta-option that defines the width, height, enlarge, and extend all at once.
+//
+//It is stored as the separate options, so setting e.g. Width afterwards overrides the width set by Size.
+//Use Compact to get the meta-option in the url.
+type Size struct {
+	Width   int
+	Height  int
+	Enlarge bool
+	Extend  *Extend
+}
+
+func (Size) Key() string {
+	return "s"
+}
+func (o Size) String() string {
+	var arguments = []interface{}{o.Width, o.Height, o.Enlarge}
+	if o.Extend != nil {
+		arguments = append(arguments, o.Extend)
+	}
+	return format(o.Key(), arguments...)
+}
+
+func (o Size) Validate() error {
+	if err := (Width{o.Width}).Validate(); err != nil {
+		return err
+	}
+	if err := (Height{o.Height}).Validate(); err != nil {
+		return err
+	}
+	if o.Extend != nil {
+		return o.Extend.Validate()
+	}
+	return nil
+}
 
 //Defines an area of the image to be processed (crop before resize).
 //
@@ -159,6 +316,18 @@ func (o Crop) String() string {
 	}
 	return format(o.Key(), arguments...)
 }
+func (o Crop) Validate() error {
+	if o.Width < 0 {
+		return optionError(o.Key(), o.Width, "width must not be negative")
+	}
+	if o.Height < 0 {
+		return optionError(o.Key(), o.Height, "height must not be negative")
+	}
+	if o.Gravity != nil {
+		return o.Gravity.Validate()
+	}
+	return nil
+}
 
 //Defines padding size in css manner. All arguments are optional but at least one dimension must be set. Padded space is filled according to background option.
 type Padding struct {
@@ -174,6 +343,14 @@ func (Padding) Key() string {
 func (o Padding) String() string {
 	return format(o.Key(), o.Top, o.Right, o.Bottom, o.Left)
 }
+func (o Padding) Validate() error {
+	for _, size := range []int{o.Top, o.Right, o.Bottom, o.Left} {
+		if size < 0 {
+			return optionError(o.Key(), size, "must not be negative")
+		}
+	}
+	return nil
+}
 
 type GravityType string
 
@@ -210,6 +387,9 @@ const (
 
 	//Focus point gravity. Offsets are floating point numbers between 0 and 1 that define the coordinates of the center of the resulting image. Treat 0 and 1 as right/left for x and top/bottom for y.
 	GravityTypeFocusPoint GravityType = "fp"
+
+	//Replicate (tile) the watermark to fill the entire image. Applicable to Watermark only, offsets define the spacing between the tiles.
+	GravityTypeReplicate GravityType = "re"
 )
 
 type GravityOffsets interface {
@@ -260,6 +440,33 @@ func (o Gravity) String() string {
 
 	return format(o.Key(), arguments...)
 }
+func (o Gravity) Validate() error {
+	switch o.Type {
+	case GravityTypeNorth, GravityTypeSouth, GravityTypeEast, GravityTypeWest,
+		GravityTypeNorthEast, GravityTypeNorthWest, GravityTypeSouthEast, GravityTypeSouthWest, GravityTypeCenter:
+		return nil
+	case GravityTypeSmart:
+		if o.Offsets != nil {
+			return optionError(o.Key(), o.Offsets, "offsets are not applicable to smart gravity")
+		}
+		return nil
+	case GravityTypeFocusPoint:
+		var x, y float64
+		switch offsets := o.Offsets.(type) {
+		case GravityFloatOffsets:
+			x, y = offsets.X, offsets.Y
+		case GravityIntegerOffsets:
+			x, y = float64(offsets.X), float64(offsets.Y)
+		default:
+			return optionError(o.Key(), o.Offsets, "focus point gravity requires offsets")
+		}
+		if x < 0 || x > 1 || y < 0 || y > 1 {
+			return optionError(o.Key(), o.Offsets, "focus point offsets must be between 0 and 1")
+		}
+		return nil
+	}
+	return optionError(o.Key(), o.Type, "unknown gravity type")
+}
 
 //When set, imgproxy will apply the sharpen filter to the resulting image
 //
@@ -275,6 +482,12 @@ func (Sharpen) Key() string {
 func (o Sharpen) String() string {
 	return format(o.Key(), o.Sigma)
 }
+func (o Sharpen) Validate() error {
+	if o.Sigma < 0 {
+		return optionError(o.Key(), o.Sigma, "must not be negative")
+	}
+	return nil
+}
 
 //Redefines quality of the resulting image, percentage. When 0, quality is assumed based on IMGPROXY_QUALITY and IMGPROXY_FORMAT_QUALITY.
 type Quality struct {
@@ -287,6 +500,120 @@ func (Quality) Key() string {
 func (o Quality) String() string {
 	return format(o.Key(), o.Quality)
 }
+func (o Quality) Validate() error {
+	if o.Quality < 0 || o.Quality > 100 {
+		return optionError(o.Key(), o.Quality, "must be between 0 and 100")
+	}
+	return nil
+}
+
+//Adds or redefines the quality of the resulting image per format (IMGPROXY_FORMAT_QUALITY), e.g. {"jpeg": 80, "webp": 70}.
+//Quality takes precedence when set.
+type FormatQuality struct {
+	Qualities map[FormatName]int
+}
+
+func (FormatQuality) Key() string {
+	return "fq"
+}
+func (o FormatQuality) String() string {
+	formats := make([]FormatName, 0, len(o.Qualities))
+	for f := range o.Qualities {
+		formats = append(formats, f)
+	}
+	// sorted, so that the url is stable
+	sort.Slice(formats, func(i, j int) bool {
+		return formats[i] < formats[j]
+	})
+
+	arguments := make([]interface{}, 0, 2*len(formats))
+	for _, f := range formats {
+		arguments = append(arguments, f, o.Qualities[f])
+	}
+	return format(o.Key(), arguments...)
+}
+func (o FormatQuality) Validate() error {
+	if len(o.Qualities) == 0 {
+		return optionError(o.Key(), o.Qualities, "at least one format is required")
+	}
+	for f, quality := range o.Qualities {
+		if f == FormatBest || f.Validate() != nil {
+			return optionError(o.Key(), f, "unknown format")
+		}
+		if quality < 0 || quality > 100 {
+			return optionError(o.Key(), quality, "must be between 0 and 100")
+		}
+	}
+	return nil
+}
+
+type AutoqualityMethodName string
+
+const (
+	//AutoqualityMethodNone disables autoquality
+	AutoqualityMethodNone AutoqualityMethodName = "none"
+	//AutoqualityMethodSize selects the quality so that the resulting image is close to Target bytes
+	AutoqualityMethodSize AutoqualityMethodName = "size"
+	//AutoqualityMethodDssim selects the quality so that the DSSIM of the resulting image is close to Target
+	AutoqualityMethodDssim AutoqualityMethodName = "dssim"
+	//AutoqualityMethodMl predicts the quality with a neural network so that the DSSIM of the resulting image is close to Target
+	AutoqualityMethodMl AutoqualityMethodName = "ml"
+)
+
+//Redefines the autoquality settings (imgproxy Pro): imgproxy selects the quality of the resulting image automatically.
+//Zero values are unset and fall back to the imgproxy configuration.
+type Autoquality struct {
+	Method AutoqualityMethodName
+	//Target value of the method: the file size in bytes for AutoqualityMethodSize, the DSSIM for the others.
+	Target float64
+	//MinQuality and MaxQuality limit the selected quality, between 1 and 100.
+	MinQuality int
+	MaxQuality int
+	//AllowedError is the allowed deviation of the DSSIM from Target (AutoqualityMethodDssim only).
+	AllowedError float64
+}
+
+func (Autoquality) Key() string {
+	return "aq"
+}
+func (o Autoquality) String() string {
+	var arguments = []interface{}{o.Method, "", "", "", ""}
+	if o.Target != 0 {
+		arguments[1] = o.Target
+	}
+	if o.MinQuality != 0 {
+		arguments[2] = o.MinQuality
+	}
+	if o.MaxQuality != 0 {
+		arguments[3] = o.MaxQuality
+	}
+	if o.AllowedError != 0 {
+		arguments[4] = o.AllowedError
+	}
+	return formatTrimmed(o.Key(), arguments...)
+}
+func (o Autoquality) Validate() error {
+	switch o.Method {
+	case "", AutoqualityMethodNone, AutoqualityMethodSize, AutoqualityMethodDssim, AutoqualityMethodMl:
+	default:
+		return optionError(o.Key(), o.Method, "unknown autoquality method")
+	}
+	if o.Target < 0 {
+		return optionError(o.Key(), o.Target, "target must not be negative")
+	}
+	for _, quality := range []int{o.MinQuality, o.MaxQuality} {
+		if quality < 0 || quality > 100 {
+			return optionError(o.Key(), quality, "quality must be between 1 and 100")
+		}
+	}
+	if o.MinQuality != 0 && o.MaxQuality != 0 && o.MinQuality > o.MaxQuality {
+		return optionError(o.Key(), o.MinQuality, "min quality must not be greater than max quality")
+	}
+	if o.AllowedError < 0 {
+		return optionError(o.Key(), o.AllowedError, "allowed error must not be negative")
+	}
+	return nil
+}
 
 //When set, imgproxy automatically degrades the quality of the image until the image is under the specified amount of bytes.
 //
@@ -301,6 +628,12 @@ func (MaxBytes) Key() string {
 func (o MaxBytes) String() string {
 	return format(o.Key(), o.MaxBytes)
 }
+func (o MaxBytes) Validate() error {
+	if o.MaxBytes < 0 {
+		return optionError(o.Key(), o.MaxBytes, "must not be negative")
+	}
+	return nil
+}
 
 //When set, imgproxy will fill the resulting image background with the specified color. HexColor is a hex-coded value of the color. Useful when you convert an image with alpha-channel to JPEG.
 type BackgroundHex struct {
@@ -313,6 +646,12 @@ func (BackgroundHex) Key() string {
 func (o BackgroundHex) String() string {
 	return format(o.Key(), o.HexColor)
 }
+func (o BackgroundHex) Validate() error {
+	if !isHexColor(o.HexColor) {
+		return optionError(o.Key(), o.HexColor, "must be a hex-coded color")
+	}
+	return nil
+}
 
 //When set, imgproxy will fill the resulting image background with the specified color. R, G, and B are red, green and blue channel values of the background color (0-255). Useful when you convert an image with alpha-channel to JPEG.
 type BackgroundRGB struct {
@@ -328,6 +667,41 @@ func (o BackgroundRGB) String() string {
 	return format(o.Key(), o.R, o.G, o.B)
 }
 
+//Color is an RGB color used by the background and color adjustment options. It is hex-coded in the url.
+type Color struct {
+	R byte
+	G byte
+	B byte
+}
+
+func (c Color) String() string {
+	return fmt.Sprintf("%02x%02x%02x", c.R, c.G, c.B)
+}
+
+//ParseHexColor parses a hex-coded color, either 6 or 3 digits long (e.g. "ffaa00" or "fa0").
+func ParseHexColor(s string) (Color, error) {
+	if !isHexColor(s) {
+		return Color{}, optionError("color", s, "must be a hex-coded color")
+	}
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	n, _ := strconv.ParseUint(s, 16, 32)
+	return Color{R: byte(n >> 16), G: byte(n >> 8), B: byte(n)}, nil
+}
+
+//When set, imgproxy will fill the resulting image background with the specified color. Useful when you convert an image with alpha-channel to JPEG.
+type Background struct {
+	Color Color
+}
+
+func (Background) Key() string {
+	return "bg"
+}
+func (o Background) String() string {
+	return format(o.Key(), o.Color)
+}
+
 //Adds alpha channel to background. alpha is a positive floating point number between 0 and 1.
 type BackgroundAlpha struct {
 	Alpha float64
@@ -339,6 +713,12 @@ func (BackgroundAlpha) Key() string {
 func (o BackgroundAlpha) String() string {
 	return format(o.Key(), o.Alpha)
 }
+func (o BackgroundAlpha) Validate() error {
+	if o.Alpha < 0 || o.Alpha > 1 {
+		return optionError(o.Key(), o.Alpha, "must be between 0 and 1")
+	}
+	return nil
+}
 
 //Defines a list of presets to be used by imgproxy. Feel free to use as many presets in a single URL as you need.
 type Presets struct {
@@ -351,6 +731,17 @@ func (Presets) Key() string {
 func (o Presets) String() string {
 	return format(o.Key(), strings.Join(o.Presets, ":"))
 }
+func (o Presets) Validate() error {
+	if len(o.Presets) == 0 {
+		return optionError(o.Key(), o.Presets, "at least one preset is required")
+	}
+	for _, preset := range o.Presets {
+		if preset == "" || strings.ContainsAny(preset, ":/") {
+			return optionError(o.Key(), preset, "invalid preset name")
+		}
+	}
+	return nil
+}
 
 //Removes surrounding background.
 type Trim struct {
@@ -370,6 +761,15 @@ func (Trim) Key() string {
 func (o Trim) String() string {
 	return format(o.Key(), o.Threshold, o.Color, o.EqualHor, o.EqualVer)
 }
+func (o Trim) Validate() error {
+	if o.Threshold < 0 {
+		return optionError(o.Key(), o.Threshold, "threshold must not be negative")
+	}
+	if o.Color != "" && !isHexColor(o.Color) {
+		return optionError(o.Key(), o.Color, "color must be a hex-coded color")
+	}
+	return nil
+}
 
 //Rotates the image on the specified angle. The orientation from the image metadata is applied before the rotation unless autorotation is disabled.
 type Rotate struct {
@@ -383,6 +783,12 @@ func (Rotate) Key() string {
 func (o Rotate) String() string {
 	return format(o.Key(), o.Angle)
 }
+func (o Rotate) Validate() error {
+	if o.Angle%90 != 0 {
+		return optionError(o.Key(), o.Angle, "must be a multiple of 90")
+	}
+	return nil
+}
 
 //When set, imgproxy will apply the gaussian blur filter to the resulting image
 type Blur struct {
@@ -396,6 +802,12 @@ func (Blur) Key() string {
 func (o Blur) String() string {
 	return format(o.Key(), o.Sigma)
 }
+func (o Blur) Validate() error {
+	if o.Sigma < 0 {
+		return optionError(o.Key(), o.Sigma, "must not be negative")
+	}
+	return nil
+}
 
 //When set, imgproxy will automatically rotate images based onon the EXIF Orientation parameter (if available in the image meta data). The orientation tag will be removed from the image anyway. Normally this is controlled by the IMGPROXY_AUTO_ROTATE configuration but this procesing option allows the configuration to be set for each request.
 type AutoRotate struct {
@@ -409,6 +821,72 @@ func (o AutoRotate) String() string {
 	return format(o.Key(), o.AutoRotate)
 }
 
+//When set, imgproxy will strip the metadata (EXIF, IPTC, etc.) from the resulting image. Normally this is controlled by the IMGPROXY_STRIP_METADATA configuration.
+type StripMetadata struct {
+	StripMetadata bool
+}
+
+func (StripMetadata) Key() string {
+	return "sm"
+}
+func (o StripMetadata) String() string {
+	return format(o.Key(), o.StripMetadata)
+}
+
+//When set, imgproxy will keep the copyright info while stripping the metadata. Normally this is controlled by the IMGPROXY_KEEP_COPYRIGHT configuration.
+type KeepCopyright struct {
+	KeepCopyright bool
+}
+
+func (KeepCopyright) Key() string {
+	return "kcr"
+}
+func (o KeepCopyright) String() string {
+	return format(o.Key(), o.KeepCopyright)
+}
+
+//When set, imgproxy will transform the embedded color profile (ICC) to sRGB and remove it from the image. Normally this is controlled by the IMGPROXY_STRIP_COLOR_PROFILE configuration.
+type StripColorProfile struct {
+	StripColorProfile bool
+}
+
+func (StripColorProfile) Key() string {
+	return "scp"
+}
+func (o StripColorProfile) String() string {
+	return format(o.Key(), o.StripColorProfile)
+}
+
+//When set, imgproxy will use the embedded thumbnail of HEIC/AVIF images instead of the main image, if present. Normally this is controlled by the IMGPROXY_ENFORCE_THUMBNAIL configuration.
+type EnforceThumbnail struct {
+	EnforceThumbnail bool
+}
+
+func (EnforceThumbnail) Key() string {
+	return "eth"
+}
+func (o EnforceThumbnail) String() string {
+	return format(o.Key(), o.EnforceThumbnail)
+}
+
+//When set, imgproxy will replace the DPI metadata of the image with the provided value (imgproxy Pro). When 0, the DPI is not changed (or reset to the default when the metadata is stripped).
+type Dpi struct {
+	Dpi int
+}
+
+func (Dpi) Key() string {
+	return "dpi"
+}
+func (o Dpi) String() string {
+	return format(o.Key(), o.Dpi)
+}
+func (o Dpi) Validate() error {
+	if o.Dpi < 0 {
+		return optionError(o.Key(), o.Dpi, "must not be negative")
+	}
+	return nil
+}
+
 //Defines a filename for Content-Disposition header. When not specified, imgproxy will get filename from the source url.
 type Filename struct {
 	Filename string
@@ -420,6 +898,510 @@ func (Filename) Key() string {
 func (o Filename) String() string {
 	return format(o.Key(), o.Filename)
 }
+func (o Filename) Validate() error {
+	if strings.ContainsAny(o.Filename, "/:") {
+		return optionError(o.Key(), o.Filename, "must not contain slashes or colons")
+	}
+	return nil
+}
+
+//Puts a watermark on the processed image. The watermark image is defined by IMGPROXY_WATERMARK_* configuration or by WatermarkUrl and WatermarkText.
+type Watermark struct {
+	//Opacity of the watermark, between 0 and 1. When 0, the watermark is disabled.
+	Opacity float64
+	//Position of the watermark, one of the gravity types except smart and focus point, or GravityTypeReplicate. Defaults to center.
+	Position GravityType
+	//Offsets of the watermark from the position edges (or the spacing between the tiles for GravityTypeReplicate).
+	Offsets GravityOffsets
+	//Scale of the watermark relative to the resulting image width. When 0, the watermark is not scaled.
+	Scale float64
+}
+
+func (Watermark) Key() string {
+	return "wm"
+}
+func (o Watermark) String() string {
+	var arguments = []interface{}{o.Opacity, o.Position}
+	if o.Offsets != nil {
+		arguments = append(arguments, o.Offsets)
+	} else {
+		arguments = append(arguments, "", "")
+	}
+	if o.Scale != 0 {
+		arguments = append(arguments, o.Scale)
+	}
+	return formatTrimmed(o.Key(), arguments...)
+}
+func (o Watermark) Validate() error {
+	if o.Opacity < 0 || o.Opacity > 1 {
+		return optionError(o.Key(), o.Opacity, "opacity must be between 0 and 1")
+	}
+	switch o.Position {
+	case "", GravityTypeNorth, GravityTypeSouth, GravityTypeEast, GravityTypeWest,
+		GravityTypeNorthEast, GravityTypeNorthWest, GravityTypeSouthEast, GravityTypeSouthWest, GravityTypeCenter,
+		GravityTypeReplicate:
+	default:
+		return optionError(o.Key(), o.Position, "unknown watermark position")
+	}
+	if o.Scale < 0 {
+		return optionError(o.Key(), o.Scale, "scale must not be negative")
+	}
+	return nil
+}
+
+//Defines the url of a custom watermark image (imgproxy Pro). The url is base64-encoded in the resulting url.
+type WatermarkUrl struct {
+	Url string
+}
+
+func (WatermarkUrl) Key() string {
+	return "wmu"
+}
+func (o WatermarkUrl) String() string {
+	return format(o.Key(), base64.RawURLEncoding.EncodeToString([]byte(o.Url)))
+}
+func (o WatermarkUrl) Validate() error {
+	if o.Url == "" {
+		return optionError(o.Key(), o.Url, "must not be empty")
+	}
+	return nil
+}
+
+//Generates a text watermark (imgproxy Pro). The text is base64-encoded in the resulting url.
+//
+//imgproxy renders the text as Pango markup. Plain text is escaped, set Markup to pass the markup as is (see PangoEscape).
+type WatermarkText struct {
+	Text   string
+	Markup bool
+}
+
+func (WatermarkText) Key() string {
+	return "wmt"
+}
+func (o WatermarkText) String() string {
+	text := o.Text
+	if !o.Markup {
+		text = PangoEscape(text)
+	}
+	return format(o.Key(), base64.RawURLEncoding.EncodeToString([]byte(text)))
+}
+func (o WatermarkText) Validate() error {
+	if o.Text == "" {
+		return optionError(o.Key(), o.Text, "must not be empty")
+	}
+	return nil
+}
+
+var pangoReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "'", "&apos;", `"`, "&quot;")
+
+// PangoEscape escapes s for use in Pango markup, e.g. to put user-provided text into a WatermarkText markup.
+func PangoEscape(s string) string {
+	return pangoReplacer.Replace(s)
+}
+
+//Defines the desired width and height of the watermark (imgproxy Pro). When 0, imgproxy calculates the dimension using the other one and the watermark aspect ratio.
+type WatermarkSize struct {
+	Width  int
+	Height int
+}
+
+func (WatermarkSize) Key() string {
+	return "wms"
+}
+func (o WatermarkSize) String() string {
+	return format(o.Key(), o.Width, o.Height)
+}
+func (o WatermarkSize) Validate() error {
+	if o.Width < 0 {
+		return optionError(o.Key(), o.Width, "width must not be negative")
+	}
+	if o.Height < 0 {
+		return optionError(o.Key(), o.Height, "height must not be negative")
+	}
+	return nil
+}
+
+//Rotates the watermark on the specified angle, clockwise (imgproxy Pro).
+type WatermarkRotate struct {
+	Angle int
+}
+
+func (WatermarkRotate) Key() string {
+	return "wmr"
+}
+func (o WatermarkRotate) String() string {
+	return format(o.Key(), o.Angle)
+}
+
+//Adds a shadow to the watermark (imgproxy Pro). Sigma defines the size of the shadow mask, 0 disables the shadow.
+type WatermarkShadow struct {
+	Sigma float64
+}
+
+func (WatermarkShadow) Key() string {
+	return "wmsh"
+}
+func (o WatermarkShadow) String() string {
+	return format(o.Key(), o.Sigma)
+}
+func (o WatermarkShadow) Validate() error {
+	if o.Sigma < 0 {
+		return optionError(o.Key(), o.Sigma, "must not be negative")
+	}
+	return nil
+}
+
+//Adjusts the brightness of the resulting image (imgproxy Pro). Brightness is an integer between -255 and 255.
+type Brightness struct {
+	Brightness int
+}
+
+func (Brightness) Key() string {
+	return "br"
+}
+func (o Brightness) String() string {
+	return format(o.Key(), o.Brightness)
+}
+func (o Brightness) Validate() error {
+	if o.Brightness < -255 || o.Brightness > 255 {
+		return optionError(o.Key(), o.Brightness, "must be between -255 and 255")
+	}
+	return nil
+}
+
+//Adjusts the contrast of the resulting image (imgproxy Pro). Contrast is a positive floating point number, 1 keeps the contrast unchanged.
+type Contrast struct {
+	Contrast float64
+}
+
+func (Contrast) Key() string {
+	return "co"
+}
+func (o Contrast) String() string {
+	return format(o.Key(), o.Contrast)
+}
+func (o Contrast) Validate() error {
+	if o.Contrast < 0 {
+		return optionError(o.Key(), o.Contrast, "must not be negative")
+	}
+	return nil
+}
+
+//Adjusts the saturation of the resulting image (imgproxy Pro). Saturation is a positive floating point number, 1 keeps the saturation unchanged.
+type Saturation struct {
+	Saturation float64
+}
+
+func (Saturation) Key() string {
+	return "sa"
+}
+func (o Saturation) String() string {
+	return format(o.Key(), o.Saturation)
+}
+func (o Saturation) Validate() error {
+	if o.Saturation < 0 {
+		return optionError(o.Key(), o.Saturation, "must not be negative")
+	}
+	return nil
+}
+
+//Adjust is a meta-option that defines the brightness, contrast, and saturation all at once (imgproxy Pro). Unset options are not changed.
+//
+//It is stored as the separate options, so setting e.g. Contrast afterwards overrides the contrast set by Adjust.
+//Use Compact to get the meta-option in the url.
+type Adjust struct {
+	Brightness *Brightness
+	Contrast   *Contrast
+	Saturation *Saturation
+}
+
+func (Adjust) Key() string {
+	return "a"
+}
+func (o Adjust) String() string {
+	var arguments = []interface{}{"", "", ""}
+	if o.Brightness != nil {
+		arguments[0] = o.Brightness
+	}
+	if o.Contrast != nil {
+		arguments[1] = o.Contrast
+	}
+	if o.Saturation != nil {
+		arguments[2] = o.Saturation
+	}
+	return formatTrimmed(o.Key(), arguments...)
+}
+func (o Adjust) Validate() error {
+	if o.Brightness != nil {
+		if err := o.Brightness.Validate(); err != nil {
+			return err
+		}
+	}
+	if o.Contrast != nil {
+		if err := o.Contrast.Validate(); err != nil {
+			return err
+		}
+	}
+	if o.Saturation != nil {
+		return o.Saturation.Validate()
+	}
+	return nil
+}
+
+//Converts the resulting image to monochrome (imgproxy Pro).
+type Monochrome struct {
+	//Intensity of the effect, between 0 and 1. When 0, the effect is disabled.
+	Intensity float64
+	//Color of the monochrome image. When nil, imgproxy uses its default (b3b3b3).
+	Color *Color
+}
+
+func (Monochrome) Key() string {
+	return "mc"
+}
+func (o Monochrome) String() string {
+	return formatTrimmed(o.Key(), o.Intensity, optionalArgument(o.Color))
+}
+func (o Monochrome) Validate() error {
+	if o.Intensity < 0 || o.Intensity > 1 {
+		return optionError(o.Key(), o.Intensity, "intensity must be between 0 and 1")
+	}
+	return nil
+}
+
+//Converts the resulting image to duotone (imgproxy Pro): the shadows are colored with Shadow and the highlights with Highlight.
+type Duotone struct {
+	//Intensity of the effect, between 0 and 1. When 0, the effect is disabled.
+	Intensity float64
+	//Color of the dark areas. When nil, imgproxy uses its default (000000).
+	Shadow *Color
+	//Color of the light areas. When nil, imgproxy uses its default (ffffff).
+	Highlight *Color
+}
+
+func (Duotone) Key() string {
+	return "dt"
+}
+func (o Duotone) String() string {
+	return formatTrimmed(o.Key(), o.Intensity, optionalArgument(o.Shadow), optionalArgument(o.Highlight))
+}
+func (o Duotone) Validate() error {
+	if o.Intensity < 0 || o.Intensity > 1 {
+		return optionError(o.Key(), o.Intensity, "intensity must be between 0 and 1")
+	}
+	return nil
+}
+
+//Places a color overlay on the resulting image (imgproxy Pro).
+type Colorize struct {
+	//Opacity of the overlay, between 0 and 1. When 0, the overlay is disabled.
+	Opacity float64
+	//Color of the overlay. When nil, imgproxy uses its default (000000).
+	Color *Color
+	//When set, imgproxy keeps the alpha channel of the image unchanged.
+	KeepAlpha bool
+}
+
+func (Colorize) Key() string {
+	return "col"
+}
+func (o Colorize) String() string {
+	var keepAlpha interface{} = ""
+	if o.KeepAlpha {
+		keepAlpha = true
+	}
+	return formatTrimmed(o.Key(), o.Opacity, optionalArgument(o.Color), keepAlpha)
+}
+func (o Colorize) Validate() error {
+	if o.Opacity < 0 || o.Opacity > 1 {
+		return optionError(o.Key(), o.Opacity, "opacity must be between 0 and 1")
+	}
+	return nil
+}
+
+//Allows redefining JPEG saving options (imgproxy Pro). Unset (nil) arguments fall back to the imgproxy configuration.
+type JpegOptions struct {
+	//When true, imgproxy saves progressive JPEG.
+	Progressive *bool
+	//When true, chrominance subsampling is disabled.
+	NoSubsample *bool
+	//When true, trellis quantisation is enabled for each 8x8 block.
+	TrellisQuant *bool
+	//When true, overshooting of samples with extreme values is enabled.
+	OvershootDeringing *bool
+	//When true, split the spectrum of DCT coefficients into separate scans. Requires progressive JPEG.
+	OptimizeScans *bool
+	//Quantization table, between 0 and 8.
+	QuantTable *int
+}
+
+func (JpegOptions) Key() string {
+	return "jpgo"
+}
+func (o JpegOptions) String() string {
+	return formatTrimmed(o.Key(),
+		optionalArgument(o.Progressive),
+		optionalArgument(o.NoSubsample),
+		optionalArgument(o.TrellisQuant),
+		optionalArgument(o.OvershootDeringing),
+		optionalArgument(o.OptimizeScans),
+		optionalArgument(o.QuantTable),
+	)
+}
+func (o JpegOptions) Validate() error {
+	if o.QuantTable != nil && (*o.QuantTable < 0 || *o.QuantTable > 8) {
+		return optionError(o.Key(), *o.QuantTable, "quant table must be between 0 and 8")
+	}
+	return nil
+}
+
+//Allows redefining PNG saving options (imgproxy Pro). Unset arguments fall back to the imgproxy configuration.
+type PngOptions struct {
+	//When true, imgproxy saves interlaced PNG.
+	Interlaced *bool
+	//When true, imgproxy quantizes the PNG to an 8-bit palette.
+	Quantize *bool
+	//Maximum number of the palette colors, between 2 and 256. When 0, the argument is unset.
+	QuantizationColors int
+}
+
+func (PngOptions) Key() string {
+	return "pngo"
+}
+func (o PngOptions) String() string {
+	var colors interface{} = ""
+	if o.QuantizationColors != 0 {
+		colors = o.QuantizationColors
+	}
+	return formatTrimmed(o.Key(), optionalArgument(o.Interlaced), optionalArgument(o.Quantize), colors)
+}
+func (o PngOptions) Validate() error {
+	if o.QuantizationColors != 0 && (o.QuantizationColors < 2 || o.QuantizationColors > 256) {
+		return optionError(o.Key(), o.QuantizationColors, "quantization colors must be between 2 and 256")
+	}
+	return nil
+}
+
+type WebpCompressionName string
+
+const (
+	WebpCompressionLossy        WebpCompressionName = "lossy"
+	WebpCompressionNearLossless WebpCompressionName = "near_lossless"
+	WebpCompressionLossless     WebpCompressionName = "lossless"
+)
+
+//Allows redefining WebP saving options (imgproxy Pro). Unset arguments fall back to the imgproxy configuration.
+type WebpOptions struct {
+	//Compression method. When empty, the argument is unset.
+	Compression WebpCompressionName
+	//When true, imgproxy uses the high quality chroma subsampling.
+	SmartSubsample *bool
+}
+
+func (WebpOptions) Key() string {
+	return "webpo"
+}
+func (o WebpOptions) String() string {
+	return formatTrimmed(o.Key(), o.Compression, optionalArgument(o.SmartSubsample))
+}
+func (o WebpOptions) Validate() error {
+	switch o.Compression {
+	case "", WebpCompressionLossy, WebpCompressionNearLossless, WebpCompressionLossless:
+		return nil
+	}
+	return optionError(o.Key(), o.Compression, "unknown compression")
+}
+
+type AvifSubsampleName string
+
+const (
+	//AvifSubsampleAuto disables chroma subsampling for the high quality images only
+	AvifSubsampleAuto AvifSubsampleName = "auto"
+	AvifSubsampleOn   AvifSubsampleName = "on"
+	AvifSubsampleOff  AvifSubsampleName = "off"
+)
+
+//Allows redefining AVIF saving options (imgproxy Pro). Unset arguments fall back to the imgproxy configuration.
+type AvifOptions struct {
+	//Chroma subsampling. When empty, the argument is unset.
+	Subsample AvifSubsampleName
+}
+
+func (AvifOptions) Key() string {
+	return "avifo"
+}
+func (o AvifOptions) String() string {
+	return formatTrimmed(o.Key(), o.Subsample)
+}
+func (o AvifOptions) Validate() error {
+	switch o.Subsample {
+	case "", AvifSubsampleAuto, AvifSubsampleOn, AvifSubsampleOff:
+		return nil
+	}
+	return optionError(o.Key(), o.Subsample, "unknown subsample mode")
+}
+
+//Allows redefining GIF saving options (imgproxy Pro). Unset arguments fall back to the imgproxy configuration.
+type GifOptions struct {
+	//When true, imgproxy optimizes the frames of animated GIFs.
+	OptimizeFrames *bool
+	//When true, imgproxy optimizes the transparency of animated GIFs.
+	OptimizeTransparency *bool
+}
+
+func (GifOptions) Key() string {
+	return "gifo"
+}
+func (o GifOptions) String() string {
+	return formatTrimmed(o.Key(), optionalArgument(o.OptimizeFrames), optionalArgument(o.OptimizeTransparency))
+}
+
+//Allows redefining the best format settings (imgproxy Pro), used when the format is FormatBest.
+//Zero values are unset and fall back to the imgproxy configuration.
+type BestFormat struct {
+	//ComplexityThreshold is the image complexity below which imgproxy considers lossless formats.
+	ComplexityThreshold float64
+	//MaxResolution in megapixels. imgproxy checks only the first of AllowedFormats for larger images.
+	MaxResolution float64
+	//ByDefault makes imgproxy use the best format when no format is set in the url.
+	ByDefault *bool
+	//AllowedFormats are the candidate formats imgproxy tries.
+	AllowedFormats []FormatName
+}
+
+func (BestFormat) Key() string {
+	return "bf"
+}
+func (o BestFormat) String() string {
+	var arguments = []interface{}{"", "", optionalArgument(o.ByDefault)}
+	if o.ComplexityThreshold != 0 {
+		arguments[0] = o.ComplexityThreshold
+	}
+	if o.MaxResolution != 0 {
+		arguments[1] = o.MaxResolution
+	}
+	for _, f := range o.AllowedFormats {
+		arguments = append(arguments, f)
+	}
+	return formatTrimmed(o.Key(), arguments...)
+}
+func (o BestFormat) Validate() error {
+	if o.ComplexityThreshold < 0 {
+		return optionError(o.Key(), o.ComplexityThreshold, "complexity threshold must not be negative")
+	}
+	if o.MaxResolution < 0 {
+		return optionError(o.Key(), o.MaxResolution, "max resolution must not be negative")
+	}
+	for _, f := range o.AllowedFormats {
+		if f == FormatBest {
+			return optionError(o.Key(), f, "best is not a candidate format")
+		}
+		if err := f.Validate(); err != nil {
+			return optionError(o.Key(), f, "unknown format")
+		}
+	}
+	return nil
+}
 
 type Raw struct {
 	OptionKey  string
@@ -433,8 +1415,73 @@ func (o Raw) String() string {
 	return format(o.Key(), o.Parameters...)
 }
 
+//FormatName is the resulting image format.
+type FormatName string
+
+const (
+	FormatJpg FormatName = "jpg"
+	//FormatJpeg is an alias of FormatJpg
+	FormatJpeg FormatName = "jpeg"
+	FormatPng  FormatName = "png"
+	FormatWebp FormatName = "webp"
+	FormatAvif FormatName = "avif"
+	FormatGif  FormatName = "gif"
+	FormatIco  FormatName = "ico"
+	FormatSvg  FormatName = "svg"
+	FormatHeic FormatName = "heic"
+	FormatBmp  FormatName = "bmp"
+	FormatTiff FormatName = "tiff"
+	//FormatMp4 converts animated images to video (imgproxy Pro)
+	FormatMp4 FormatName = "mp4"
+	//FormatBest makes imgproxy pick the format producing the smallest image (imgproxy Pro, see BestFormat)
+	FormatBest FormatName = "best"
+)
+
+func (f FormatName) Validate() error {
+	switch f {
+	case FormatJpg, FormatJpeg, FormatPng, FormatWebp, FormatAvif, FormatGif, FormatIco, FormatSvg,
+		FormatHeic, FormatBmp, FormatTiff, FormatMp4, FormatBest:
+		return nil
+	}
+	return optionError("format", f, "unknown format")
+}
+
+var mimeTypes = map[FormatName]string{
+	FormatJpg:  "image/jpeg",
+	FormatJpeg: "image/jpeg",
+	FormatPng:  "image/png",
+	FormatWebp: "image/webp",
+	FormatAvif: "image/avif",
+	FormatGif:  "image/gif",
+	FormatIco:  "image/x-icon",
+	FormatSvg:  "image/svg+xml",
+	FormatHeic: "image/heif",
+	FormatBmp:  "image/bmp",
+	FormatTiff: "image/tiff",
+	FormatMp4:  "video/mp4",
+}
+
+//MimeType returns the media type of the format, e.g. image/webp. It is empty for unknown formats and FormatBest.
+func (f FormatName) MimeType() string {
+	return mimeTypes[FormatName(strings.ToLower(string(f)))]
+}
+
+//Format defines the resulting image format. It is added to the source url as the extension
+//(or as the format processing option, see FormatAsOption). Empty format keeps the source image format.
 type Format struct {
-	Format string
+	Format FormatName
+}
+
+func (o Format) Validate() error {
+	if o.Format == "" {
+		return nil
+	}
+	return o.Format.Validate()
+}
+
+//FormatAsOption puts the format into the url as the format (f) processing option instead of the source url extension.
+type FormatAsOption struct {
+	AsOption bool
 }
 
 type SourceUrl struct {
@@ -445,6 +1492,21 @@ type PlainSourceUrl struct {
 	Plain bool
 }
 
+// EncryptedSourceUrl makes the source url encrypted with AES-CBC ("enc/" source urls, imgproxy Pro).
+// Requires EncryptionKey or EncryptionKeyRaw to be set.
+type EncryptedSourceUrl struct {
+	Encrypted bool
+}
+
+// EncryptionKey is a hex-encoded AES key used to encrypt source urls (IMGPROXY_SOURCE_URL_ENCRYPTION_KEY).
+type EncryptionKey struct {
+	Key string
+}
+
+type EncryptionKeyRaw struct {
+	KeyRaw []byte
+}
+
 type Key struct {
 	Key string
 }
@@ -461,6 +1523,28 @@ type SaltRaw struct {
 	SaltRaw []byte
 }
 
+// Keys sets a Keyring: urls are signed with its active pair and verified against all its pairs.
+// Key, Salt, KeyRaw and SaltRaw applied later replace the Keyring. An empty Keyring is an error.
+type Keys struct {
+	Keyring *Keyring
+}
+
+// CustomSigner makes urls signed with the Signer instead of the key and salt (see Signer).
+// Key, Salt, KeyRaw, SaltRaw and Keys applied later replace the Signer, nil Signer restores the default signing.
+type CustomSigner struct {
+	Signer Signer
+}
+
+// RequireSignature makes Build fail with ErrSignatureRequired instead of building an "insecure" url
+// when neither key and salt nor a Signer are set. Use it on production builders to catch a missing configuration early.
+type RequireSignature struct {
+	Require bool
+}
+
+// Insecure makes the url unsigned explicitly: the key, salt, Keyring and Signer are dropped and the "insecure" signature
+// is used even if RequireSignature is set. Key, Salt, KeyRaw, SaltRaw, Keys and CustomSigner applied later sign the url again.
+type Insecure struct{}
+
 type Endpoint struct {
 	Endpoint string
 }
@@ -468,3 +1552,21 @@ type Endpoint struct {
 type SignatureSize struct {
 	SignatureSize int
 }
+
+func (o SignatureSize) Validate() error {
+	if o.SignatureSize < 0 || o.SignatureSize > 32 {
+		return optionError("signature_size", o.SignatureSize, "must be between 1 and 32 (or 0 for the default)")
+	}
+	return nil
+}
+
+// Compact makes the url shorter by folding the resizing type, width, height, enlarge and extend options
+// into a single resize or size meta-option, and the brightness, contrast and saturation options into adjust.
+type Compact struct {
+	Compact bool
+}
+
+// Strict enables validation of the options (see Validator). Invalid, unsupported and nil options make New and WithOptions fail.
+type Strict struct {
+	Strict bool
+}
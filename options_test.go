@@ -19,6 +19,7 @@ func Test_format(t *testing.T) {
 		{name: "no arguments", args: args{key: "k", arguments: nil}, want: ""},
 		{name: "one int argument", args: args{key: "k", arguments: []interface{}{1}}, want: "1"},
 		{name: "mixed-type arguments", args: args{key: "k", arguments: []interface{}{1, "z", ts{}}}, want: "1:z:tstring"},
+		{name: "boolean arguments", args: args{key: "k", arguments: []interface{}{true, false}}, want: "true:false"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -28,3 +29,109 @@ func Test_format(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessingOption_String(t *testing.T) {
+	tests := []struct {
+		name   string
+		option ProcessingOption
+		want   string
+	}{
+		{name: "watermark opacity", option: Watermark{Opacity: 0.5}, want: "0.5"},
+		{name: "watermark position", option: Watermark{Opacity: 1, Position: GravityTypeSouthEast}, want: "1:soea"},
+		{name: "watermark offsets", option: Watermark{Opacity: 1, Position: GravityTypeNorthWest, Offsets: GravityIntegerOffsets{10, 20}}, want: "1:nowe:10:20"},
+		{name: "watermark scale w/o offsets", option: Watermark{Opacity: 0.7, Position: GravityTypeReplicate, Scale: 0.2}, want: "0.7:re:::0.2"},
+		{name: "watermark full", option: Watermark{Opacity: 0.7, Position: GravityTypeReplicate, Offsets: GravityIntegerOffsets{5, 5}, Scale: 0.2}, want: "0.7:re:5:5:0.2"},
+		{name: "watermark url", option: WatermarkUrl{"https://example.com/logo.png"}, want: "aHR0cHM6Ly9leGFtcGxlLmNvbS9sb2dvLnBuZw"},
+		{name: "watermark text", option: WatermarkText{Text: "<b>&"}, want: "Jmx0O2ImZ3Q7JmFtcDs"},
+		{name: "watermark markup", option: WatermarkText{Text: "<b>&amp;</b>", Markup: true}, want: "PGI-JmFtcDs8L2I-"},
+		{name: "watermark size", option: WatermarkSize{Width: 100}, want: "100:0"},
+		{name: "watermark rotate", option: WatermarkRotate{-45}, want: "-45"},
+		{name: "watermark shadow", option: WatermarkShadow{1.5}, want: "1.5"},
+		{name: "background", option: Background{Color{R: 255, G: 10, B: 0}}, want: "ff0a00"},
+		{name: "brightness", option: Brightness{-20}, want: "-20"},
+		{name: "adjust", option: Adjust{Contrast: &Contrast{1.5}}, want: ":1.5"},
+		{name: "monochrome", option: Monochrome{Intensity: 1}, want: "1"},
+		{name: "monochrome color", option: Monochrome{Intensity: 0.5, Color: &Color{R: 0xb3, G: 0xb3, B: 0xb3}}, want: "0.5:b3b3b3"},
+		{name: "duotone", option: Duotone{Intensity: 1, Shadow: &Color{}, Highlight: &Color{R: 255, G: 255}}, want: "1:000000:ffff00"},
+		{name: "duotone highlight only", option: Duotone{Intensity: 1, Highlight: &Color{R: 255}}, want: "1::ff0000"},
+		{name: "colorize", option: Colorize{Opacity: 0.3, Color: &Color{B: 255}}, want: "0.3:0000ff"},
+		{name: "colorize keep alpha", option: Colorize{Opacity: 0.3, KeepAlpha: true}, want: "0.3::true"},
+		{name: "jpeg options empty", option: JpegOptions{}, want: ""},
+		{name: "jpeg options progressive", option: JpegOptions{Progressive: Bool(true)}, want: "true"},
+		{name: "jpeg options gaps", option: JpegOptions{Progressive: Bool(false), TrellisQuant: Bool(true), QuantTable: Int(0)}, want: "false::true:::0"},
+		{name: "png options", option: PngOptions{Quantize: Bool(true), QuantizationColors: 64}, want: ":true:64"},
+		{name: "png options interlaced", option: PngOptions{Interlaced: Bool(true)}, want: "true"},
+		{name: "webp options", option: WebpOptions{Compression: WebpCompressionNearLossless}, want: "near_lossless"},
+		{name: "webp options smart subsample", option: WebpOptions{SmartSubsample: Bool(true)}, want: ":true"},
+		{name: "avif options", option: AvifOptions{Subsample: AvifSubsampleOff}, want: "off"},
+		{name: "format quality", option: FormatQuality{map[FormatName]int{FormatWebp: 70, FormatJpeg: 80, FormatAvif: 50}}, want: "avif:50:jpeg:80:webp:70"},
+		{name: "autoquality", option: Autoquality{Method: AutoqualityMethodDssim, Target: 0.02, MinQuality: 70, MaxQuality: 80, AllowedError: 0.001}, want: "dssim:0.02:70:80:0.001"},
+		{name: "autoquality method only", option: Autoquality{Method: AutoqualityMethodMl}, want: "ml"},
+		{name: "autoquality gaps", option: Autoquality{Method: AutoqualityMethodSize, Target: 10000, MaxQuality: 90}, want: "size:10000::90"},
+		{name: "best format", option: BestFormat{ByDefault: Bool(true), AllowedFormats: []FormatName{FormatAvif, FormatWebp, FormatJpg}}, want: "::true:avif:webp:jpg"},
+		{name: "best format threshold", option: BestFormat{ComplexityThreshold: 5.5}, want: "5.5"},
+		{name: "auto rotate", option: AutoRotate{true}, want: "true"},
+		{name: "strip metadata", option: StripMetadata{true}, want: "true"},
+		{name: "keep copyright", option: KeepCopyright{true}, want: "true"},
+		{name: "strip color profile", option: StripColorProfile{false}, want: "false"},
+		{name: "enforce thumbnail", option: EnforceThumbnail{true}, want: "true"},
+		{name: "dpi", option: Dpi{300}, want: "300"},
+		{name: "gif options", option: GifOptions{OptimizeTransparency: Bool(true)}, want: ":true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.option.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPangoEscape(t *testing.T) {
+	if got, want := PangoEscape(`Tom & "Jerry's" <shop>`), "Tom &amp; &quot;Jerry&apos;s&quot; &lt;shop&gt;"; got != want {
+		t.Errorf("PangoEscape() = %v, want %v", got, want)
+	}
+}
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Color
+		wantErr bool
+	}{
+		{s: "ff0a00", want: Color{R: 255, G: 10}},
+		{s: "FA0", want: Color{R: 255, G: 170}},
+		{s: "red", wantErr: true},
+		{s: "ff0a0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseHexColor(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHexColor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseHexColor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatName_MimeType(t *testing.T) {
+	tests := []struct {
+		format FormatName
+		want   string
+	}{
+		{format: FormatJpg, want: "image/jpeg"},
+		{format: "WEBP", want: "image/webp"},
+		{format: FormatBest, want: ""},
+		{format: "jpg ", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			if got := tt.format.MimeType(); got != tt.want {
+				t.Errorf("MimeType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package imgproxyurl
+
+import (
+	"sort"
+)
+
+// OptionsOrderName defines the order of the processing options in the url.
+type OptionsOrderName string
+
+const (
+	// OptionsOrderAlphabetical sorts the options alphabetically. This makes urls stable regardless of the order the options were set in.
+	OptionsOrderAlphabetical OptionsOrderName = "alphabetical"
+	// OptionsOrderInsertion keeps the options in the order they were set. Overriding an option moves it to the end,
+	// so that it's applied after the options set before it.
+	OptionsOrderInsertion OptionsOrderName = "insertion"
+	// OptionsOrderPresetsFirst puts presets first and keeps the rest of the options in the order they were set,
+	// so that the options override the values set by the presets.
+	OptionsOrderPresetsFirst OptionsOrderName = "presets_first"
+)
+
+// OptionsOrder defines the order of the processing options in the url. imgproxy applies the options in the url order,
+// so a later option overrides an earlier one (e.g. a value set by a preset). Default is OptionsOrderAlphabetical.
+type OptionsOrder struct {
+	Order OptionsOrderName
+}
+
+func (o OptionsOrder) Validate() error {
+	switch o.Order {
+	case "", OptionsOrderAlphabetical, OptionsOrderInsertion, OptionsOrderPresetsFirst:
+		return nil
+	}
+	return optionError("options_order", o.Order, "unknown options order")
+}
+
+type processingOption struct {
+	key string
+	// name is the spelling of the option in a parsed url (e.g. "width" for "w"), the key is used when empty
+	name  string
+	value string
+}
+
+func (o processingOption) String() string {
+	name := o.name
+	if name == "" {
+		name = o.key
+	}
+	if o.value == "" {
+		return name
+	}
+	return name + ":" + o.value
+}
+
+// processingOptions is a list of processing options in the order they were set. Keys are unique, except in parsed urls:
+// these keep repeated segments (e.g. several presets), since imgproxy applies every one of them.
+type processingOptions []processingOption
+
+// set appends the option with the given key, removing its previous value if there is one.
+func (o processingOptions) set(key string, value string) processingOptions {
+	return o.setNamed(key, "", value)
+}
+
+// setNamed is set for an option spelled as name in the url (see processingOption).
+func (o processingOptions) setNamed(key string, name string, value string) processingOptions {
+	return append(o.without(key), processingOption{key: key, name: name, value: value})
+}
+
+// replace replaces the value of the option with the given key keeping its position, or appends a new option.
+func (o processingOptions) replace(key string, value string) processingOptions {
+	for i := range o {
+		if o[i].key == key {
+			o[i].value = value
+			return o
+		}
+	}
+	return append(o, processingOption{key: key, value: value})
+}
+
+// without removes all the options with the given key. Meta-options kept as is (in parsed urls) lose the corresponding
+// argument and are removed when no arguments are left.
+func (o processingOptions) without(key string) processingOptions {
+	result := o[:0]
+	for _, option := range o {
+		if option.key == key {
+			continue
+		}
+		if _, ok := option.metaArgument(key); ok {
+			if option = option.withoutMetaArgument(key); option.value == "" {
+				continue
+			}
+		}
+		result = append(result, option)
+	}
+	return result
+}
+
+// get returns the value of the option with the given key, including the arguments of the meta-options kept as is
+// (in parsed urls). The last one wins when the key is repeated.
+func (o processingOptions) get(key string) (string, bool) {
+	for i := len(o) - 1; i >= 0; i-- {
+		if o[i].key == key {
+			return o[i].value, true
+		}
+		if value, ok := o[i].metaArgument(key); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func (o processingOptions) clone() processingOptions {
+	if o == nil {
+		return nil
+	}
+	return append(make(processingOptions, 0, len(o)), o...)
+}
+
+// ordered returns the options as url parts in the given order.
+func (o processingOptions) ordered(order OptionsOrderName) []string {
+	parts := make([]string, 0, len(o))
+	switch order {
+	case OptionsOrderInsertion:
+		for _, option := range o {
+			parts = append(parts, option.String())
+		}
+	case OptionsOrderPresetsFirst:
+		for _, option := range o {
+			if option.key == (Presets{}).Key() {
+				parts = append(parts, option.String())
+			}
+		}
+		for _, option := range o {
+			if option.key != (Presets{}).Key() {
+				parts = append(parts, option.String())
+			}
+		}
+	default:
+		for _, option := range o {
+			parts = append(parts, option.String())
+		}
+		// sort url parts to make sure the resulting url is stable
+		sort.Strings(parts)
+	}
+	return parts
+}
@@ -0,0 +1,37 @@
+package imgproxyurl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUrl_optionParts_order(t *testing.T) {
+	options := []Option{Width{100}, Presets{[]string{"thumb"}}, Quality{80}, Height{50}, Width{200}}
+	tests := []struct {
+		name  string
+		order OptionsOrderName
+		want  []string
+	}{
+		{name: "default", order: "", want: []string{"h:50", "pr:thumb", "q:80", "w:200"}},
+		{name: "alphabetical", order: OptionsOrderAlphabetical, want: []string{"h:50", "pr:thumb", "q:80", "w:200"}},
+		{name: "insertion", order: OptionsOrderInsertion, want: []string{"pr:thumb", "q:80", "h:50", "w:200"}},
+		{name: "presets first", order: OptionsOrderPresetsFirst, want: []string{"pr:thumb", "q:80", "h:50", "w:200"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := New("local:///a.jpg", append(options, OptionsOrder{tt.order})...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := u.Options(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Options() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionsOrder_Validate(t *testing.T) {
+	if _, err := New("local:///a.jpg", Strict{true}, OptionsOrder{"random"}); err == nil {
+		t.Errorf("New() expected an error")
+	}
+}
@@ -0,0 +1,238 @@
+package imgproxyurl
+
+import (
+	"encoding/base64"
+	"github.com/pkg/errors"
+	"net/url"
+	"strings"
+)
+
+const insecureSignature = "insecure"
+
+// Parse loads an imgproxy url (as produced by Url.String) back into a Url.
+// Settings which can not be recovered from the url itself (key, salt, etc.) are taken from the global settings,
+// the options passed are applied on top of the parsed ones.
+// The processing options keep their order in the url (see OptionsOrderInsertion) unless OptionsOrder is passed.
+// Repeated options, full option names and meta-options are kept as they are, so that the url is rebuilt as it was,
+// while the options are still looked up and overridden by their short keys (e.g. Width overrides the width of a resize).
+func Parse(rawUrl string, options ...Option) (*Url, error) {
+	return Default().Parse(rawUrl, options...)
+}
+
+// Parse loads an imgproxy url back into a Url using the settings of the Builder (see the package-level Parse).
+func (b *Builder) Parse(rawUrl string, options ...Option) (*Url, error) {
+	result, err := b.base.clone(options)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, signature, path := splitUrl(rawUrl, result.endpoint)
+	if signature == "" {
+		return nil, errors.New("no signature in url")
+	}
+	if err := result.parsePath(path); err != nil {
+		return nil, err
+	}
+	result.endpoint = endpoint
+	if signature != insecureSignature {
+		decoded, err := base64.RawURLEncoding.DecodeString(signature)
+		if err != nil {
+			return nil, errors.WithMessage(err, "signature")
+		}
+		result.signatureSize = len(decoded)
+	}
+
+	// options passed explicitly take precedence over the parsed ones
+	if err := result.applyOptions(options...); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// splitUrl splits an imgproxy url into the endpoint, the signature and the signed path (starting with a slash).
+func splitUrl(rawUrl string, endpoint string) (string, string, string) {
+	endpoint = strings.TrimRight(endpoint, "/")
+	if endpoint == "" || !strings.HasPrefix(rawUrl, endpoint+"/") {
+		endpoint = ""
+		if i := strings.Index(rawUrl, "://"); i >= 0 {
+			end := strings.IndexByte(rawUrl[i+3:], '/')
+			if end < 0 {
+				return rawUrl, "", ""
+			}
+			endpoint = rawUrl[:i+3+end]
+		}
+	}
+
+	rest := strings.TrimPrefix(rawUrl[len(endpoint):], "/")
+	i := strings.IndexByte(rest, '/')
+	if i < 0 {
+		return endpoint, rest, ""
+	}
+	return endpoint, rest[:i], rest[i:]
+}
+
+// optionKeys maps the full names of the processing options to their short keys.
+var optionKeys = map[string]string{
+	"resize":              "rs",
+	"size":                "s",
+	"resizing_type":       "rt",
+	"resizing_algorithm":  "ra",
+	"width":               "w",
+	"height":              "h",
+	"enlarge":             "el",
+	"extend":              "ex",
+	"gravity":             "g",
+	"crop":                "c",
+	"padding":             "pd",
+	"trim":                "t",
+	"rotate":              "rot",
+	"quality":             "q",
+	"max_bytes":           "mb",
+	"background":          "bg",
+	"background_alpha":    "bga",
+	"blur":                "bl",
+	"sharpen":             "sh",
+	"preset":              "pr",
+	"auto_rotate":         "ar",
+	"filename":            "fn",
+	"watermark":           "wm",
+	"watermark_url":       "wmu",
+	"watermark_text":      "wmt",
+	"watermark_size":      "wms",
+	"watermark_rotate":    "wmr",
+	"watermark_shadow":    "wmsh",
+	"brightness":          "br",
+	"contrast":            "co",
+	"saturation":          "sa",
+	"adjust":              "a",
+	"monochrome":          "mc",
+	"duotone":             "dt",
+	"colorize":            "col",
+	"jpeg_options":        "jpgo",
+	"png_options":         "pngo",
+	"webp_options":        "webpo",
+	"avif_options":        "avifo",
+	"gif_options":         "gifo",
+	"format_quality":      "fq",
+	"autoquality":         "aq",
+	"format":              formatOptionKey,
+	"ext":                 formatOptionKey,
+	"best_format":         "bf",
+	"strip_metadata":      "sm",
+	"keep_copyright":      "kcr",
+	"strip_color_profile": "scp",
+	"enforce_thumbnail":   "eth",
+}
+
+func (u *Url) parsePath(path string) error {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(segments) == 0 || segments[len(segments)-1] == "" {
+		return errors.New("no source url in path")
+	}
+
+	// everything after "plain" or "enc" is a plain or encrypted source url, otherwise the last segment is a base64-encoded one
+	sourceStart := len(segments) - 1
+	for i, segment := range segments {
+		if segment == "plain" || segment == "enc" {
+			sourceStart = i
+			break
+		}
+	}
+
+	options := make(processingOptions, 0, sourceStart)
+	var formatOption string
+	for _, segment := range segments[:sourceStart] {
+		if segment == "" {
+			return errors.New("empty processing option")
+		}
+		var name, value string
+		if i := strings.IndexByte(segment, ':'); i >= 0 {
+			name, value = segment[:i], segment[i+1:]
+		} else {
+			name = segment
+		}
+		// the options are looked up and overridden by their short keys, the url keeps the full names
+		key := name
+		if short, ok := optionKeys[name]; ok {
+			key = short
+		}
+		switch {
+		case key == formatOptionKey:
+			formatOption = value
+			// keep the position of the format option, its value is taken from the format
+			options = options.setNamed(key, name, value)
+		default:
+			// repeated options are kept, imgproxy applies every one of them (e.g. several presets).
+			// meta-options are kept as they are too, see processingOption.metaArgument
+			options = append(options, processingOption{key: key, name: name, value: value})
+		}
+	}
+	u.options = options
+	// imgproxy applies the options in the url order, re-sorting them would change the result (and the signature)
+	u.optionsOrder = OptionsOrderInsertion
+
+	if err := u.decodeSourceUrl(segments[sourceStart:]); err != nil {
+		return err
+	}
+	// the extension takes precedence over the format option, as in imgproxy
+	switch {
+	case u.format != "":
+		u.formatAsOption = false
+		u.options = u.options.without(formatOptionKey)
+	case formatOption != "":
+		u.format = formatOption
+		u.formatAsOption = true
+	}
+	return nil
+}
+
+func (u *Url) decodeSourceUrl(segments []string) error {
+	if segments[0] == "plain" {
+		encodedUrl := strings.Join(segments[1:], "/")
+		u.format = ""
+		if i := strings.LastIndexByte(encodedUrl, '@'); i >= 0 {
+			encodedUrl, u.format = encodedUrl[:i], encodedUrl[i+1:]
+		}
+		sourceUrl, err := url.QueryUnescape(encodedUrl)
+		if err != nil {
+			return errors.WithMessage(err, "plain source url")
+		}
+		u.plainSourceUrl = true
+		u.encryptedSourceUrl = false
+		u.sourceUrl = sourceUrl
+		return nil
+	}
+
+	if segments[0] == "enc" {
+		encryptedUrl := strings.Join(segments[1:], "")
+		u.format = ""
+		if i := strings.LastIndexByte(encryptedUrl, '.'); i >= 0 {
+			encryptedUrl, u.format = encryptedUrl[:i], encryptedUrl[i+1:]
+		}
+		if u.encryptionKey == nil {
+			return errors.New("encrypted source url requires an encryption key")
+		}
+		sourceUrl, err := DecryptSourceUrl(u.encryptionKey, encryptedUrl)
+		if err != nil {
+			return errors.WithMessage(err, "encrypted source url")
+		}
+		u.encryptedSourceUrl = true
+		u.sourceUrl = sourceUrl
+		return nil
+	}
+
+	encodedUrl := segments[0]
+	u.format = ""
+	if i := strings.LastIndexByte(encodedUrl, '.'); i >= 0 {
+		encodedUrl, u.format = encodedUrl[:i], encodedUrl[i+1:]
+	}
+	sourceUrl, err := base64.RawURLEncoding.DecodeString(encodedUrl)
+	if err != nil {
+		return errors.WithMessage(err, "base64 source url")
+	}
+	u.plainSourceUrl = false
+	u.encryptedSourceUrl = false
+	u.sourceUrl = string(sourceUrl)
+	return nil
+}
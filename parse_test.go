@@ -0,0 +1,202 @@
+package imgproxyurl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		u       *Url
+		options []Option
+	}{
+		{name: "signed base64", u: func() *Url {
+			u, _ := New(
+				"local:///o/t/otRO1jl3IUVa.jpg",
+				Width{200},
+				Height{200},
+				Format{"png"},
+				ResizingType{ResizingTypeFill},
+				Key{testKey},
+				Salt{testSalt},
+				Endpoint{"https://example.com/"},
+			)
+			return u
+		}(), options: []Option{Key{testKey}, Salt{testSalt}}},
+		{name: "insecure plain", u: func() *Url {
+			u, _ := New(
+				"https://example.com/images/a b.jpg?x=1",
+				PlainSourceUrl{true},
+				Format{"webp"},
+				Raw{OptionKey: "raw", Parameters: []interface{}{1, 2, "test"}},
+				Raw{OptionKey: "noargs"},
+				Extend{Extend: true, Gravity: &Gravity{Type: GravityTypeNorth, Offsets: GravityIntegerOffsets{X: 1, Y: 2}}},
+			)
+			return u
+		}()},
+		{name: "truncated signature, endpoint with path", u: func() *Url {
+			u, _ := New(
+				"s3://bucket/image.png",
+				Width{100},
+				Key{testKey},
+				Salt{testSalt},
+				SignatureSize{8},
+				Endpoint{"https://example.com/imgproxy"},
+			)
+			return u
+		}(), options: []Option{Key{testKey}, Salt{testSalt}, Endpoint{"https://example.com/imgproxy"}}},
+		{name: "format as option", u: func() *Url {
+			u, _ := New("local:///a.jpg", Width{100}, Format{FormatBest}, FormatAsOption{true}, BestFormat{AllowedFormats: []FormatName{FormatAvif, FormatWebp}})
+			return u
+		}()},
+		{name: "insertion order", u: func() *Url {
+			u, _ := New("local:///a.jpg", OptionsOrder{OptionsOrderInsertion}, Width{100}, Presets{[]string{"x"}}, Height{50})
+			return u
+		}()},
+		{name: "no endpoint, no options", u: func() *Url {
+			u, _ := New("local:///a.jpg")
+			return u
+		}()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.u.String()
+			got, err := Parse(want, tt.options...)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got.String() != want {
+				t.Errorf("Parse().String() = %v, want %v", got.String(), want)
+			}
+			if got.sourceUrl != tt.u.sourceUrl || got.plainSourceUrl != tt.u.plainSourceUrl || got.format != tt.u.format {
+				t.Errorf("Parse() source = %v (plain %v, format %v), want %v (plain %v, format %v)",
+					got.sourceUrl, got.plainSourceUrl, got.format, tt.u.sourceUrl, tt.u.plainSourceUrl, tt.u.format)
+			}
+			if !reflect.DeepEqual(got.Options(), tt.u.Options()) {
+				t.Errorf("Parse() options = %v, want %v", got.Options(), tt.u.Options())
+			}
+		})
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		rawUrl string
+	}{
+		{name: "no path", rawUrl: "https://example.com"},
+		{name: "no source", rawUrl: "https://example.com/insecure"},
+		{name: "empty source", rawUrl: "https://example.com/insecure/w:100/"},
+		{name: "malformed signature", rawUrl: "https://example.com/%%%/w:100/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "malformed base64", rawUrl: "https://example.com/insecure/w:100/%%%"},
+		{name: "malformed plain", rawUrl: "https://example.com/insecure/w:100/plain/%zz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.rawUrl); err == nil {
+				t.Errorf("Parse() expected an error")
+			}
+		})
+	}
+}
+
+func TestParse_order(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		want    string
+	}{
+		{name: "url order", want: "/insecure/w:100/pr:a/h:50/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "explicit order", options: []Option{OptionsOrder{OptionsOrderAlphabetical}}, want: "/insecure/h:50/pr:a/w:100/bG9jYWw6Ly8vYS5qcGc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := Parse("/insecure/w:100/pr:a/h:50/bG9jYWw6Ly8vYS5qcGc", tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := u.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_format(t *testing.T) {
+	tests := []struct {
+		name        string
+		rawUrl      string
+		format      string
+		asOption    bool
+		wantOptions []string
+	}{
+		{name: "extension", rawUrl: "/insecure/w:100/bG9jYWw6Ly8vYS5qcGc.webp", format: "webp", wantOptions: []string{"w:100"}},
+		{name: "option", rawUrl: "/insecure/f:webp/w:100/bG9jYWw6Ly8vYS5qcGc", format: "webp", asOption: true, wantOptions: []string{"f:webp", "w:100"}},
+		{name: "full option name", rawUrl: "/insecure/format:avif/w:100/plain/local:%2F%2F%2Fa.jpg", format: "avif", asOption: true, wantOptions: []string{"format:avif", "w:100"}},
+		{name: "extension overrides option", rawUrl: "/insecure/f:webp/w:100/bG9jYWw6Ly8vYS5qcGc.png", format: "png", wantOptions: []string{"w:100"}},
+		{name: "none", rawUrl: "/insecure/w:100/bG9jYWw6Ly8vYS5qcGc", wantOptions: []string{"w:100"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := Parse(tt.rawUrl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.Format() != tt.format || u.formatAsOption != tt.asOption {
+				t.Errorf("Parse() format = %v (as option %v), want %v (as option %v)", u.Format(), u.formatAsOption, tt.format, tt.asOption)
+			}
+			if !reflect.DeepEqual(u.Options(), tt.wantOptions) {
+				t.Errorf("Parse() options = %v, want %v", u.Options(), tt.wantOptions)
+			}
+		})
+	}
+}
+
+func TestParse_fullNames(t *testing.T) {
+	const rawUrl = "/insecure/width:100/q:80/bG9jYWw6Ly8vYS5qcGc"
+	u, err := Parse(rawUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.String(); got != rawUrl {
+		t.Errorf("String() = %v, want %v", got, rawUrl)
+	}
+	if got, ok := u.Option(Width{}.Key()); !ok || got != "100" {
+		t.Errorf("Option() = %v, %v, want 100, true", got, ok)
+	}
+	if w, h, err := u.ResultSize(1000, 500); err != nil || w != 100 || h != 50 {
+		t.Errorf("ResultSize() = %v, %v, %v, want 100, 50, <nil>", w, h, err)
+	}
+
+	u2, err := u.WithOptions(Width{200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u2.String(), "/insecure/q:80/w:200/bG9jYWw6Ly8vYS5qcGc"; got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+}
+
+func TestParse_repeated(t *testing.T) {
+	const rawUrl = "/insecure/pr:a/w:1/pr:b/eA"
+	u, err := Parse(rawUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.String(); got != rawUrl {
+		t.Errorf("String() = %v, want %v", got, rawUrl)
+	}
+	if got, _ := u.Option(Presets{}.Key()); got != "b" {
+		t.Errorf("Option() = %v, want b", got)
+	}
+
+	// WithOptions still replaces all of them
+	u2, err := u.WithOptions(Presets{[]string{"c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u2.String(), "/insecure/w:1/pr:c/eA"; got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+}
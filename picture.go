@@ -0,0 +1,142 @@
+package imgproxyurl
+
+import (
+	"github.com/pkg/errors"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// PictureSource is an art direction source of a Picture. Its options override the options of the base url
+// when the media query matches (e.g. a different Crop or Gravity for mobile).
+type PictureSource struct {
+	Media   string
+	Options []Option
+}
+
+// Picture renders a <picture> element with format fallbacks and art direction.
+type Picture struct {
+	// Url is the base url of the image.
+	Url *Url
+	// Formats are the target formats in the order of preference (e.g. avif, webp, jpg).
+	// The last one is used for the <img> fallback. The format of Url is used when empty.
+	// The formats are validated in strict mode.
+	Formats []FormatName
+	// Sources are the art direction sources in the order of preference (the first matching media query wins).
+	Sources []PictureSource
+	// Widths is a width ladder for the srcset attributes. A single url is used for every source when empty.
+	Widths []int
+	// Sizes is the value of the sizes attribute, makes sense with Widths only.
+	Sizes string
+	// Alt is the alt attribute of the <img>.
+	Alt string
+	// Width and Height are the width and height attributes of the <img>. Omitted when 0.
+	Width  int
+	Height int
+}
+
+// HTML renders the <picture> element. All the attribute values are escaped.
+func (p Picture) HTML() (string, error) {
+	if p.Url == nil {
+		return "", errors.New("picture has no url")
+	}
+
+	formats := p.Formats
+	if len(formats) == 0 {
+		formats = []FormatName{FormatName(p.Url.format)}
+	}
+	fallback := formats[len(formats)-1]
+
+	var b strings.Builder
+	b.WriteString("<picture>")
+	for _, source := range p.Sources {
+		for _, f := range formats {
+			if err := p.writeSource(&b, source.Media, f, source.Options); err != nil {
+				return "", errors.WithMessagef(err, "source %q", source.Media)
+			}
+		}
+	}
+	for _, f := range formats[:len(formats)-1] {
+		if err := p.writeSource(&b, "", f, nil); err != nil {
+			return "", err
+		}
+	}
+
+	u, err := p.Url.WithOptions(Format{fallback})
+	if err != nil {
+		return "", err
+	}
+	src := u
+	if len(p.Widths) > 0 {
+		largest := p.Widths[0]
+		for _, width := range p.Widths {
+			if width > largest {
+				largest = width
+			}
+		}
+		if src, err = u.WithOptions(Width{largest}); err != nil {
+			return "", err
+		}
+	}
+	srcUrl, err := src.Build()
+	if err != nil {
+		return "", err
+	}
+	b.WriteString("<img")
+	writeAttribute(&b, "src", srcUrl)
+	if len(p.Widths) > 0 {
+		srcset, err := u.SrcsetWidths(p.Widths...)
+		if err != nil {
+			return "", err
+		}
+		writeAttribute(&b, "srcset", srcset.String())
+		writeAttribute(&b, "sizes", p.Sizes)
+	}
+	if p.Width > 0 {
+		writeAttribute(&b, "width", strconv.Itoa(p.Width))
+	}
+	if p.Height > 0 {
+		writeAttribute(&b, "height", strconv.Itoa(p.Height))
+	}
+	b.WriteString(` alt="` + html.EscapeString(p.Alt) + `">`)
+	b.WriteString("</picture>")
+
+	return b.String(), nil
+}
+
+func (p Picture) writeSource(b *strings.Builder, media string, format FormatName, options []Option) error {
+	u, err := p.Url.WithOptions(append(append([]Option(nil), options...), Format{format})...)
+	if err != nil {
+		return err
+	}
+
+	srcset, err := u.Build()
+	if err != nil {
+		return err
+	}
+	if len(p.Widths) > 0 {
+		s, err := u.SrcsetWidths(p.Widths...)
+		if err != nil {
+			return err
+		}
+		srcset = s.String()
+	}
+
+	b.WriteString("<source")
+	writeAttribute(b, "media", media)
+	writeAttribute(b, "type", format.MimeType())
+	writeAttribute(b, "srcset", srcset)
+	if len(p.Widths) > 0 {
+		writeAttribute(b, "sizes", p.Sizes)
+	}
+	b.WriteString(">")
+	return nil
+}
+
+// writeAttribute writes an escaped attribute, empty values are omitted.
+func writeAttribute(b *strings.Builder, name string, value string) {
+	if value == "" {
+		return
+	}
+	b.WriteString(" " + name + `="` + html.EscapeString(value) + `"`)
+}
@@ -0,0 +1,64 @@
+package imgproxyurl
+
+import (
+	"testing"
+)
+
+func TestPicture_HTML(t *testing.T) {
+	u, err := New("local:///a.jpg", Width{400})
+	if err != nil {
+		t.Fatal(err)
+	}
+	strict, err := u.WithOptions(Strict{true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned, err := u.WithOptions(RequireSignature{true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		picture Picture
+		want    string
+		wantErr bool
+	}{
+		{name: "single format", picture: Picture{Url: u, Alt: `"a" & <b>`}, want: `<picture>` +
+			`<img src="/insecure/w:400/bG9jYWw6Ly8vYS5qcGc" alt="&#34;a&#34; &amp; &lt;b&gt;">` +
+			`</picture>`},
+		{name: "format fallbacks", picture: Picture{Url: u, Formats: []FormatName{FormatAvif, FormatWebp, FormatJpg}, Width: 400, Height: 300}, want: `<picture>` +
+			`<source type="image/avif" srcset="/insecure/w:400/bG9jYWw6Ly8vYS5qcGc.avif">` +
+			`<source type="image/webp" srcset="/insecure/w:400/bG9jYWw6Ly8vYS5qcGc.webp">` +
+			`<img src="/insecure/w:400/bG9jYWw6Ly8vYS5qcGc.jpg" width="400" height="300" alt="">` +
+			`</picture>`},
+		{name: "art direction", picture: Picture{
+			Url:     u,
+			Formats: []FormatName{FormatWebp, FormatJpg},
+			Sources: []PictureSource{{Media: "(max-width: 600px)", Options: []Option{Crop{Width: 0.5}}}},
+		}, want: `<picture>` +
+			`<source media="(max-width: 600px)" type="image/webp" srcset="/insecure/c:0.5:0/w:400/bG9jYWw6Ly8vYS5qcGc.webp">` +
+			`<source media="(max-width: 600px)" type="image/jpeg" srcset="/insecure/c:0.5:0/w:400/bG9jYWw6Ly8vYS5qcGc.jpg">` +
+			`<source type="image/webp" srcset="/insecure/w:400/bG9jYWw6Ly8vYS5qcGc.webp">` +
+			`<img src="/insecure/w:400/bG9jYWw6Ly8vYS5qcGc.jpg" alt="">` +
+			`</picture>`},
+		{name: "widths", picture: Picture{Url: u, Formats: []FormatName{FormatWebp, FormatPng}, Widths: []int{200, 400}, Sizes: "50vw"}, want: `<picture>` +
+			`<source type="image/webp" srcset="/insecure/w:200/bG9jYWw6Ly8vYS5qcGc.webp 200w, /insecure/w:400/bG9jYWw6Ly8vYS5qcGc.webp 400w" sizes="50vw">` +
+			`<img src="/insecure/w:400/bG9jYWw6Ly8vYS5qcGc.png" srcset="/insecure/w:200/bG9jYWw6Ly8vYS5qcGc.png 200w, /insecure/w:400/bG9jYWw6Ly8vYS5qcGc.png 400w" sizes="50vw" alt="">` +
+			`</picture>`},
+		{name: "no url", picture: Picture{}, wantErr: true},
+		{name: "invalid widths", picture: Picture{Url: u, Widths: []int{-1}}, wantErr: true},
+		{name: "signature required", picture: Picture{Url: unsigned, Formats: []FormatName{FormatWebp, FormatJpg}}, wantErr: true},
+		{name: "unknown format", picture: Picture{Url: strict, Formats: []FormatName{FormatWebp, "jpg "}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.picture.HTML()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HTML() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("HTML() =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
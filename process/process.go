@@ -0,0 +1,420 @@
+// Package process is a pure-Go image processor implementing a subset of imgproxy processing options.
+//
+// It is meant for tests and local development without an imgproxy instance: the results approximate
+// the ones of imgproxy (the sizes match, the resampling and blur filters differ).
+//
+// Supported options: width, height, resizing type, dpr, enlarge, extend, crop, gravity, padding, rotate,
+// background, blur and quality. The formats are jpeg, png and gif.
+package process
+
+import (
+	"github.com/penyaev/imgproxyurl"
+	"github.com/pkg/errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Process applies the processing options of u to img.
+func Process(u *imgproxyurl.Url, img image.Image) (image.Image, error) {
+	bounds := img.Bounds()
+	g, err := u.Geometry(bounds.Dx(), bounds.Dy())
+	if err != nil {
+		return nil, err
+	}
+
+	gravity, err := optionGravity(u, imgproxyurl.Gravity{}.Key(), 0)
+	if err != nil {
+		return nil, err
+	}
+	cropGravity, err := optionGravity(u, imgproxyurl.Crop{}.Key(), 2)
+	if err != nil {
+		return nil, err
+	}
+	if cropGravity == nil {
+		cropGravity = gravity
+	}
+	extendGravity, err := optionGravity(u, imgproxyurl.Extend{}.Key(), 1)
+	if err != nil {
+		return nil, err
+	}
+	background, err := optionBackground(u)
+	if err != nil {
+		return nil, err
+	}
+	sigma, err := optionFloat(u, imgproxyurl.Blur{}.Key())
+	if err != nil {
+		return nil, err
+	}
+
+	result := toNRGBA(img)
+	result = rotate(result, g.Angle)
+	result = crop(result, g.CropWidth, g.CropHeight, cropGravity)
+	result = resize(result, g.ScaledWidth, g.ScaledHeight)
+	result = crop(result, g.ResultCropWidth, g.ResultCropHeight, gravity)
+	// imgproxy blurs the image before extending and padding it, so the background stays sharp
+	if sigma > 0 {
+		result = blur(result, sigma)
+	}
+	result = embed(result, g.ExtendedWidth, g.ExtendedHeight, extendGravity, background)
+	result = pad(result, g.Padding, background)
+	if background != nil {
+		result = flatten(result, *background)
+	}
+
+	return result, nil
+}
+
+// Encode encodes img in the format of u. sourceFormat is used when u has no format set.
+// The quality option of u (or its format quality for jpeg when the quality is not set) is used for jpeg.
+func Encode(w io.Writer, u *imgproxyurl.Url, img image.Image, sourceFormat string) error {
+	format := u.Format()
+	if format == "" {
+		format = sourceFormat
+	}
+
+	switch strings.ToLower(format) {
+	case "jpg", "jpeg":
+		quality, err := optionFloat(u, imgproxyurl.Quality{}.Key())
+		if err != nil {
+			return err
+		}
+		if quality == 0 {
+			if quality, err = optionFormatQuality(u, "jpeg", "jpg"); err != nil {
+				return err
+			}
+		}
+		options := &jpeg.Options{Quality: jpeg.DefaultQuality}
+		if quality > 0 {
+			options.Quality = int(quality)
+		}
+		return jpeg.Encode(w, img, options)
+	case "png":
+		return png.Encode(w, img)
+	case "gif":
+		return gif.Encode(w, img, nil)
+	}
+	return errors.Errorf("unsupported format %q", format)
+}
+
+// gravity is a parsed gravity option.
+type gravity struct {
+	Type imgproxyurl.GravityType
+	X, Y float64
+}
+
+// optionGravity parses the gravity arguments of the option with the given key starting at the argument skip.
+func optionGravity(u *imgproxyurl.Url, key string, skip int) (*gravity, error) {
+	value, ok := u.Option(key)
+	if !ok {
+		return nil, nil
+	}
+	args := strings.Split(value, ":")
+	if len(args) <= skip || args[skip] == "" {
+		return nil, nil
+	}
+	args = args[skip:]
+
+	g := &gravity{Type: imgproxyurl.GravityType(args[0])}
+	if len(args) >= 3 {
+		var err error
+		if g.X, err = strconv.ParseFloat(args[1], 64); err != nil {
+			return nil, errors.WithMessagef(err, "%s: gravity x offset", key)
+		}
+		if g.Y, err = strconv.ParseFloat(args[2], 64); err != nil {
+			return nil, errors.WithMessagef(err, "%s: gravity y offset", key)
+		}
+	}
+	return g, nil
+}
+
+func optionBackground(u *imgproxyurl.Url) (*color.NRGBA, error) {
+	value, ok := u.Option(imgproxyurl.BackgroundRGB{}.Key())
+	if !ok || value == "" {
+		return nil, nil
+	}
+	c := color.NRGBA{A: 255}
+	if args := strings.Split(value, ":"); len(args) == 3 {
+		for i, p := range []*uint8{&c.R, &c.G, &c.B} {
+			n, err := strconv.ParseUint(args[i], 10, 8)
+			if err != nil {
+				return nil, errors.WithMessage(err, "background")
+			}
+			*p = uint8(n)
+		}
+	} else {
+		hex := value
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		n, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || len(hex) != 6 {
+			return nil, errors.Errorf("background: invalid color %q", value)
+		}
+		c.R, c.G, c.B = uint8(n>>16), uint8(n>>8), uint8(n)
+	}
+
+	if value, ok := u.Option(imgproxyurl.BackgroundAlpha{}.Key()); ok {
+		alpha, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, errors.WithMessage(err, "background alpha")
+		}
+		c.A = uint8(math.Round(255 * math.Max(0, math.Min(1, alpha))))
+	}
+	return &c, nil
+}
+
+// optionFormatQuality returns the quality of the first of the formats found in the format quality option.
+func optionFormatQuality(u *imgproxyurl.Url, formats ...string) (float64, error) {
+	value, ok := u.Option(imgproxyurl.FormatQuality{}.Key())
+	if !ok {
+		return 0, nil
+	}
+	args := strings.Split(value, ":")
+	for _, f := range formats {
+		for i := 0; i+1 < len(args); i += 2 {
+			if args[i] != f {
+				continue
+			}
+			quality, err := strconv.ParseFloat(args[i+1], 64)
+			if err != nil {
+				return 0, errors.WithMessagef(err, "format quality %s", f)
+			}
+			return quality, nil
+		}
+	}
+	return 0, nil
+}
+
+func optionFloat(u *imgproxyurl.Url, key string) (float64, error) {
+	value, ok := u.Option(key)
+	if !ok {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(strings.Split(value, ":")[0], 64)
+	if err != nil {
+		return 0, errors.WithMessage(err, key)
+	}
+	return f, nil
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	bounds := img.Bounds()
+	result := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(result, result.Bounds(), img, bounds.Min, draw.Src)
+	return result
+}
+
+// rotate rotates img clockwise by angle (0, 90, 180 or 270).
+func rotate(img *image.NRGBA, angle int) *image.NRGBA {
+	if angle == 0 {
+		return img
+	}
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	rw, rh := w, h
+	if angle != 180 {
+		rw, rh = h, w
+	}
+	result := image.NewNRGBA(image.Rect(0, 0, rw, rh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var rx, ry int
+			switch angle {
+			case 90:
+				rx, ry = h-1-y, x
+			case 180:
+				rx, ry = w-1-x, h-1-y
+			case 270:
+				rx, ry = y, w-1-x
+			}
+			result.SetNRGBA(rx, ry, img.NRGBAAt(x, y))
+		}
+	}
+	return result
+}
+
+// position returns the top-left corner of an inner area within an outer one the same way imgproxy places it.
+// The area is kept within the outer one.
+func position(outerWidth, outerHeight, innerWidth, innerHeight int, g *gravity) (int, int) {
+	if g == nil {
+		g = &gravity{Type: imgproxyurl.GravityTypeCenter}
+	}
+
+	var left, top int
+	if g.Type == imgproxyurl.GravityTypeFocusPoint {
+		left = int(math.Round(float64(outerWidth)*g.X)) - innerWidth/2
+		top = int(math.Round(float64(outerHeight)*g.Y)) - innerHeight/2
+	} else {
+		offX, offY := int(g.X), int(g.Y)
+		left = (outerWidth-innerWidth+1)/2 + offX
+		top = (outerHeight-innerHeight+1)/2 + offY
+		switch g.Type {
+		case imgproxyurl.GravityTypeNorth, imgproxyurl.GravityTypeNorthEast, imgproxyurl.GravityTypeNorthWest:
+			top = offY
+		case imgproxyurl.GravityTypeSouth, imgproxyurl.GravityTypeSouthEast, imgproxyurl.GravityTypeSouthWest:
+			top = outerHeight - innerHeight - offY
+		}
+		switch g.Type {
+		case imgproxyurl.GravityTypeEast, imgproxyurl.GravityTypeNorthEast, imgproxyurl.GravityTypeSouthEast:
+			left = outerWidth - innerWidth - offX
+		case imgproxyurl.GravityTypeWest, imgproxyurl.GravityTypeNorthWest, imgproxyurl.GravityTypeSouthWest:
+			left = offX
+		}
+	}
+
+	clamp := func(v, max int) int {
+		if max < 0 {
+			return max / 2
+		}
+		if v < 0 {
+			return 0
+		}
+		if v > max {
+			return max
+		}
+		return v
+	}
+	return clamp(left, outerWidth-innerWidth), clamp(top, outerHeight-innerHeight)
+}
+
+func crop(img *image.NRGBA, width int, height int, g *gravity) *image.NRGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if width >= w && height >= h {
+		return img
+	}
+	left, top := position(w, h, width, height, g)
+	result := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(result, result.Bounds(), img, image.Pt(left, top), draw.Src)
+	return result
+}
+
+// resize resamples img to the given size with bilinear interpolation.
+func resize(img *image.NRGBA, width int, height int) *image.NRGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if width == w && height == h {
+		return img
+	}
+
+	result := image.NewNRGBA(image.Rect(0, 0, width, height))
+	xScale, yScale := float64(w)/float64(width), float64(h)/float64(height)
+	for y := 0; y < height; y++ {
+		sy := math.Max(0, (float64(y)+0.5)*yScale-0.5)
+		y0 := int(sy)
+		y1 := y0 + 1
+		if y1 >= h {
+			y1 = h - 1
+		}
+		fy := sy - float64(y0)
+		for x := 0; x < width; x++ {
+			sx := math.Max(0, (float64(x)+0.5)*xScale-0.5)
+			x0 := int(sx)
+			x1 := x0 + 1
+			if x1 >= w {
+				x1 = w - 1
+			}
+			fx := sx - float64(x0)
+
+			i := result.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				top := float64(img.Pix[img.PixOffset(x0, y0)+c])*(1-fx) + float64(img.Pix[img.PixOffset(x1, y0)+c])*fx
+				bottom := float64(img.Pix[img.PixOffset(x0, y1)+c])*(1-fx) + float64(img.Pix[img.PixOffset(x1, y1)+c])*fx
+				result.Pix[i+c] = uint8(math.Round(top*(1-fy) + bottom*fy))
+			}
+		}
+	}
+	return result
+}
+
+// embed places img on a canvas of the given size filled with background (transparent when nil).
+func embed(img *image.NRGBA, width int, height int, g *gravity, background *color.NRGBA) *image.NRGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if width <= w && height <= h {
+		return img
+	}
+	left, top := position(width, height, w, h, g)
+	result := canvas(width, height, background)
+	draw.Draw(result, image.Rect(left, top, left+w, top+h), img, image.Point{}, draw.Src)
+	return result
+}
+
+// pad adds padding (top, right, bottom, left) filled with background (transparent when nil).
+func pad(img *image.NRGBA, padding [4]int, background *color.NRGBA) *image.NRGBA {
+	if padding == [4]int{} {
+		return img
+	}
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	result := canvas(w+padding[1]+padding[3], h+padding[0]+padding[2], background)
+	draw.Draw(result, image.Rect(padding[3], padding[0], padding[3]+w, padding[0]+h), img, image.Point{}, draw.Src)
+	return result
+}
+
+func canvas(width int, height int, background *color.NRGBA) *image.NRGBA {
+	result := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if background != nil {
+		draw.Draw(result, result.Bounds(), image.NewUniform(*background), image.Point{}, draw.Src)
+	}
+	return result
+}
+
+// flatten draws img over the background color.
+func flatten(img *image.NRGBA, background color.NRGBA) *image.NRGBA {
+	result := canvas(img.Bounds().Dx(), img.Bounds().Dy(), &background)
+	draw.Draw(result, result.Bounds(), img, image.Point{}, draw.Over)
+	return result
+}
+
+// blur applies a separable gaussian blur.
+func blur(img *image.NRGBA, sigma float64) *image.NRGBA {
+	radius := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, 2*radius+1)
+	var sum float64
+	for i := range kernel {
+		d := float64(i - radius)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	pass := func(src *image.NRGBA, dx int, dy int) *image.NRGBA {
+		dst := image.NewNRGBA(src.Bounds())
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				var acc [4]float64
+				for k, weight := range kernel {
+					sx := clampInt(x+(k-radius)*dx, 0, w-1)
+					sy := clampInt(y+(k-radius)*dy, 0, h-1)
+					i := src.PixOffset(sx, sy)
+					for c := 0; c < 4; c++ {
+						acc[c] += float64(src.Pix[i+c]) * weight
+					}
+				}
+				i := dst.PixOffset(x, y)
+				for c := 0; c < 4; c++ {
+					dst.Pix[i+c] = uint8(math.Round(acc[c]))
+				}
+			}
+		}
+		return dst
+	}
+	return pass(pass(img, 1, 0), 0, 1)
+}
+
+func clampInt(v int, min int, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,135 @@
+package process
+
+import (
+	"bytes"
+	"github.com/penyaev/imgproxyurl"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+var (
+	red  = color.NRGBA{R: 255, A: 255}
+	blue = color.NRGBA{B: 255, A: 255}
+)
+
+// testImage returns a 200x100 image: red left half, blue right half.
+func testImage() image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 200, 100))
+	draw.Draw(img, image.Rect(0, 0, 100, 100), image.NewUniform(red), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(100, 0, 200, 100), image.NewUniform(blue), image.Point{}, draw.Src)
+	return img
+}
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    []imgproxyurl.Option
+		wantWidth  int
+		wantHeight int
+		// wantColors maps points of the result to the expected colors
+		wantColors map[image.Point]color.NRGBA
+	}{
+		{name: "no options", wantWidth: 200, wantHeight: 100,
+			wantColors: map[image.Point]color.NRGBA{{10, 10}: red, {190, 90}: blue}},
+		{name: "fit", options: []imgproxyurl.Option{imgproxyurl.Width{W: 100}, imgproxyurl.Height{H: 100}}, wantWidth: 100, wantHeight: 50,
+			wantColors: map[image.Point]color.NRGBA{{10, 10}: red, {90, 40}: blue}},
+		{name: "fill with west gravity", options: []imgproxyurl.Option{
+			imgproxyurl.Width{W: 50}, imgproxyurl.Height{H: 100}, imgproxyurl.ResizingType{ResizingType: imgproxyurl.ResizingTypeFill},
+			imgproxyurl.Gravity{Type: imgproxyurl.GravityTypeWest},
+		}, wantWidth: 50, wantHeight: 100,
+			wantColors: map[image.Point]color.NRGBA{{0, 0}: red, {49, 99}: red}},
+		{name: "crop with east gravity", options: []imgproxyurl.Option{
+			imgproxyurl.Crop{Width: 50, Height: 50, Gravity: &imgproxyurl.Gravity{Type: imgproxyurl.GravityTypeEast}},
+		}, wantWidth: 50, wantHeight: 50,
+			wantColors: map[image.Point]color.NRGBA{{0, 0}: blue, {49, 49}: blue}},
+		{name: "rotate", options: []imgproxyurl.Option{imgproxyurl.Rotate{Angle: 90}}, wantWidth: 100, wantHeight: 200,
+			wantColors: map[image.Point]color.NRGBA{{50, 10}: red, {50, 190}: blue}},
+		{name: "padding with background", options: []imgproxyurl.Option{
+			imgproxyurl.Padding{Top: 10, Right: 10, Bottom: 10, Left: 10}, imgproxyurl.BackgroundRGB{R: 0, G: 255, B: 0},
+		}, wantWidth: 220, wantHeight: 120,
+			wantColors: map[image.Point]color.NRGBA{{0, 0}: {G: 255, A: 255}, {15, 15}: red}},
+		{name: "extend with hex background", options: []imgproxyurl.Option{
+			imgproxyurl.Width{W: 300}, imgproxyurl.Height{H: 300}, imgproxyurl.Extend{Extend: true}, imgproxyurl.BackgroundHex{HexColor: "0f0"},
+		}, wantWidth: 300, wantHeight: 300,
+			wantColors: map[image.Point]color.NRGBA{{0, 0}: {G: 255, A: 255}, {60, 150}: red, {240, 150}: blue}},
+		{name: "blur", options: []imgproxyurl.Option{imgproxyurl.Blur{Sigma: 2}}, wantWidth: 200, wantHeight: 100,
+			wantColors: map[image.Point]color.NRGBA{{10, 10}: red, {190, 90}: blue}},
+		{name: "blur with padding", options: []imgproxyurl.Option{
+			imgproxyurl.Blur{Sigma: 3}, imgproxyurl.Padding{Top: 10, Right: 10, Bottom: 10, Left: 10}, imgproxyurl.BackgroundRGB{R: 0, G: 255, B: 0},
+		}, wantWidth: 220, wantHeight: 120,
+			wantColors: map[image.Point]color.NRGBA{{9, 60}: {G: 255, A: 255}, {210, 60}: {G: 255, A: 255}, {60, 60}: red}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := imgproxyurl.New("local:///a.png", tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Process(u, testImage())
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if w, h := got.Bounds().Dx(), got.Bounds().Dy(); w != tt.wantWidth || h != tt.wantHeight {
+				t.Errorf("Process() size = %dx%d, want %dx%d", w, h, tt.wantWidth, tt.wantHeight)
+			}
+			for p, want := range tt.wantColors {
+				if c := color.NRGBAModel.Convert(got.At(p.X, p.Y)).(color.NRGBA); c != want {
+					t.Errorf("Process() color at %v = %v, want %v", p, c, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	for _, format := range []string{"jpg", "png", "gif"} {
+		t.Run(format, func(t *testing.T) {
+			u, err := imgproxyurl.New("local:///a.png", imgproxyurl.Format{Format: imgproxyurl.FormatName(format)}, imgproxyurl.Quality{Quality: 50})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b bytes.Buffer
+			if err := Encode(&b, u, testImage(), "png"); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			_, decoded, err := image.DecodeConfig(&b)
+			if err != nil {
+				t.Fatalf("DecodeConfig() error = %v", err)
+			}
+			want := format
+			if format == "jpg" {
+				want = "jpeg"
+			}
+			if decoded != want {
+				t.Errorf("Encode() format = %v, want %v", decoded, want)
+			}
+		})
+	}
+
+	u, _ := imgproxyurl.New("local:///a.png", imgproxyurl.Format{Format: "avif"})
+	if err := Encode(&bytes.Buffer{}, u, testImage(), "png"); err == nil {
+		t.Errorf("Encode() expected an error")
+	}
+
+	// the format quality is used when the quality is not set
+	encode := func(options ...imgproxyurl.Option) []byte {
+		u, err := imgproxyurl.New("local:///a.png", append([]imgproxyurl.Option{imgproxyurl.Format{Format: "jpg"}}, options...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if err := Encode(&b, u, testImage(), "png"); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		return b.Bytes()
+	}
+	formatQuality := imgproxyurl.FormatQuality{Qualities: map[imgproxyurl.FormatName]int{imgproxyurl.FormatJpeg: 10, imgproxyurl.FormatWebp: 90}}
+	if !bytes.Equal(encode(formatQuality), encode(imgproxyurl.Quality{Quality: 10})) {
+		t.Errorf("Encode() ignored the format quality")
+	}
+	if !bytes.Equal(encode(formatQuality, imgproxyurl.Quality{Quality: 90}), encode(imgproxyurl.Quality{Quality: 90})) {
+		t.Errorf("Encode() preferred the format quality over the quality")
+	}
+}
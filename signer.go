@@ -0,0 +1,74 @@
+package imgproxyurl
+
+import (
+	"crypto/sha256"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrSignatureRequired is returned by Build for urls without a key, salt or Signer when RequireSignature is set.
+	ErrSignatureRequired = errors.New("signature is required but key and salt are not set")
+	// ErrIncompleteKeySalt is returned by Build when only one of key and salt is set.
+	ErrIncompleteKeySalt = errors.New("only one of key and salt is set")
+)
+
+// Signer signs imgproxy urls. Sign receives the signed part of the url (the processing options and the source url,
+// starting with a slash) and returns the signature the way it appears in the url.
+//
+// Implement it to keep the keys out of the application memory, e.g. in a separate signing service (see CustomSigner).
+type Signer interface {
+	Sign(path string) (string, error)
+}
+
+// SignerFunc is an adapter to use ordinary functions as Signers.
+type SignerFunc func(path string) (string, error)
+
+// Sign calls f(path).
+func (f SignerFunc) Sign(path string) (string, error) {
+	return f(path)
+}
+
+// HMACSigner is the default Signer: HMAC-SHA256 of salt and path with key, truncated to SignatureSize bytes
+// (0 means full-size signatures) and base64url-encoded, the same way imgproxy checks it.
+type HMACSigner struct {
+	Key           []byte
+	Salt          []byte
+	SignatureSize int
+}
+
+// Sign returns the signature of path.
+func (s HMACSigner) Sign(path string) (string, error) {
+	if s.SignatureSize < 0 || s.SignatureSize > sha256.Size {
+		return "", errors.Errorf("invalid signature size %d", s.SignatureSize)
+	}
+	return sign(s.Key, s.Salt, s.SignatureSize, path), nil
+}
+
+// activeSigner returns the Signer for u: the custom one, the HMAC one when key and salt are set,
+// nil for insecure urls (see Insecure) and when key or salt is missing or empty.
+func (u *Url) activeSigner() Signer {
+	if u.insecure {
+		return nil
+	}
+	if u.signer != nil {
+		return u.signer
+	}
+	if len(u.key) == 0 || len(u.salt) == 0 {
+		return nil
+	}
+	return HMACSigner{Key: u.key, Salt: u.salt, SignatureSize: u.signatureSize}
+}
+
+// checkSigner reports why u can't be signed when a Signer is expected. Urls made insecure explicitly always pass.
+func (u *Url) checkSigner() error {
+	if u.insecure || u.activeSigner() != nil {
+		return nil
+	}
+	if len(u.key) != 0 || len(u.salt) != 0 {
+		return ErrIncompleteKeySalt
+	}
+	if u.requireSignature {
+		return ErrSignatureRequired
+	}
+	return nil
+}
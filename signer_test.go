@@ -0,0 +1,98 @@
+package imgproxyurl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUrl_Build(t *testing.T) {
+	errSigner := errors.New("signing service is unavailable")
+	remote := SignerFunc(func(path string) (string, error) {
+		// a stand-in for a signing service holding the keys
+		return HMACSigner{Key: []byte("key"), Salt: []byte("salt"), SignatureSize: 8}.Sign(path)
+	})
+
+	tests := []struct {
+		name    string
+		options []Option
+		want    string
+		wantErr error
+	}{
+		{name: "insecure", want: "/insecure/w:100/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "hmac", options: []Option{KeyRaw{[]byte("key")}, SaltRaw{[]byte("salt")}, SignatureSize{8}}, want: "/0fZn5UaXfqg/w:100/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "custom signer", options: []Option{CustomSigner{remote}}, want: "/0fZn5UaXfqg/w:100/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "custom signer replaced by key/salt", options: []Option{CustomSigner{remote}, Key{testKey}, Salt{testSalt}, SignatureSize{8}}, want: "/0Y54HB3QgPE/w:100/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "signer error", options: []Option{CustomSigner{SignerFunc(func(string) (string, error) {
+			return "", errSigner
+		})}}, wantErr: errSigner},
+		{name: "key w/o salt", options: []Option{Key{testKey}}, wantErr: ErrIncompleteKeySalt},
+		{name: "salt w/o key", options: []Option{SaltRaw{[]byte("salt")}}, wantErr: ErrIncompleteKeySalt},
+		{name: "empty salt", options: []Option{Key{testKey}, Salt{""}}, wantErr: ErrIncompleteKeySalt},
+		{name: "empty key", options: []Option{Key{""}, Salt{testSalt}}, wantErr: ErrIncompleteKeySalt},
+		{name: "empty raw salt", options: []Option{KeyRaw{[]byte("key")}, SaltRaw{[]byte{}}}, wantErr: ErrIncompleteKeySalt},
+		{name: "empty key and salt", options: []Option{Key{""}, Salt{""}}, want: "/insecure/w:100/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "signature required", options: []Option{RequireSignature{true}}, wantErr: ErrSignatureRequired},
+		{name: "signature required, signed", options: []Option{RequireSignature{true}, KeyRaw{[]byte("key")}, SaltRaw{[]byte("salt")}, SignatureSize{8}}, want: "/0fZn5UaXfqg/w:100/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "explicitly insecure", options: []Option{RequireSignature{true}, Key{testKey}, Salt{testSalt}, Insecure{}}, want: "/insecure/w:100/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "explicitly insecure w/ half key/salt", options: []Option{Key{testKey}, Insecure{}}, want: "/insecure/w:100/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "signed again after insecure", options: []Option{Insecure{}, CustomSigner{remote}}, want: "/0fZn5UaXfqg/w:100/bG9jYWw6Ly8vYS5qcGc"},
+		{name: "invalid signature size", options: []Option{Key{testKey}, Salt{testSalt}, SignatureSize{33}}, wantErr: errAny},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := New("local:///a.jpg", append([]Option{Width{100}}, tt.options...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := u.Build()
+			if tt.wantErr != nil {
+				if err == nil || (tt.wantErr != errAny && !errors.Is(err, tt.wantErr)) {
+					t.Errorf("Build() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if u.String() != "" {
+					t.Errorf("String() = %v, want empty string", u.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Build() = %v, want %v", got, tt.want)
+			}
+			if u.String() != got {
+				t.Errorf("String() = %v, want %v", u.String(), got)
+			}
+		})
+	}
+}
+
+// errAny matches any error in the tests.
+var errAny = errors.New("any error")
+
+func TestUrl_VerifyCustomSigner(t *testing.T) {
+	signer := HMACSigner{Key: []byte("key"), Salt: []byte("salt")}
+	u, err := New("local:///a.jpg", Width{100}, CustomSigner{signer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !u.Signed() {
+		t.Errorf("Signed() = false, want true")
+	}
+	if err := u.Verify(u.String()); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	hmac, err := New("local:///a.jpg", Width{100}, KeyRaw{signer.Key}, SaltRaw{signer.Salt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hmac.Verify(u.String()); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	if err := u.Verify(strings.Replace(u.String(), "w:100", "w:200", 1)); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("Verify() error = %v, wantErr %v", err, ErrSignatureMismatch)
+	}
+}
@@ -0,0 +1,313 @@
+package imgproxyurl
+
+import (
+	"github.com/pkg/errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Geometry describes how imgproxy transforms the image size step by step.
+type Geometry struct {
+	// Angle is the rotation angle normalized to 0, 90, 180 or 270.
+	Angle int
+	// RotatedWidth and RotatedHeight are the source dimensions after the rotation.
+	RotatedWidth, RotatedHeight int
+	// CropWidth and CropHeight are the size of the area cropped before resizing.
+	CropWidth, CropHeight int
+	// ScaledWidth and ScaledHeight are the dimensions after resizing.
+	ScaledWidth, ScaledHeight int
+	// ResultCropWidth and ResultCropHeight are the dimensions after cropping the resized image to the requested size.
+	ResultCropWidth, ResultCropHeight int
+	// ExtendedWidth and ExtendedHeight are the dimensions after extending.
+	ExtendedWidth, ExtendedHeight int
+	// Padding in pixels: top, right, bottom, left.
+	Padding [4]int
+	// Width and Height are the resulting image dimensions.
+	Width, Height int
+}
+
+// ResultSize calculates the size of the image imgproxy will return for a source image of the given size.
+// It replicates imgproxy's resizing math for Crop, Width, Height, ResizingType, Dpr, Enlarge, Extend, Padding and Rotate.
+// Options which can't be calculated without the image itself (e.g. Trim) and presets are ignored.
+func (u *Url) ResultSize(srcWidth int, srcHeight int) (int, int, error) {
+	g, err := u.Geometry(srcWidth, srcHeight)
+	if err != nil {
+		return 0, 0, err
+	}
+	return g.Width, g.Height, nil
+}
+
+// sizeOptions are the option values the result size depends on.
+type sizeOptions struct {
+	width, height int
+	resizingType  ResizingTypeName
+	dpr           float64
+	enlarge       bool
+	extend        bool
+	cropWidth     float64
+	cropHeight    float64
+	padding       [4]int
+	angle         int
+}
+
+func (u *Url) sizeOptions() (sizeOptions, error) {
+	o := sizeOptions{resizingType: ResizingTypeFit, dpr: 1}
+	var err error
+	get := func(key string) ([]string, bool) {
+		value, ok := u.options.get(key)
+		if !ok {
+			return nil, false
+		}
+		return strings.Split(value, ":"), true
+	}
+
+	if args, ok := get(Width{}.Key()); ok {
+		if o.width, err = strconv.Atoi(args[0]); err != nil {
+			return o, errors.WithMessage(err, "width")
+		}
+	}
+	if args, ok := get(Height{}.Key()); ok {
+		if o.height, err = strconv.Atoi(args[0]); err != nil {
+			return o, errors.WithMessage(err, "height")
+		}
+	}
+	if args, ok := get(ResizingType{}.Key()); ok {
+		o.resizingType = ResizingTypeName(args[0])
+		if err := (ResizingType{o.resizingType}).Validate(); err != nil {
+			return o, err
+		}
+	}
+	if args, ok := get(Dpr{}.Key()); ok {
+		if o.dpr, err = strconv.ParseFloat(args[0], 64); err != nil {
+			return o, errors.WithMessage(err, "dpr")
+		}
+		if o.dpr <= 0 {
+			return o, optionError(Dpr{}.Key(), o.dpr, "must be greater than 0")
+		}
+	}
+	if args, ok := get(Enlarge{}.Key()); ok {
+		if o.enlarge, err = parseBool(args[0]); err != nil {
+			return o, errors.WithMessage(err, "enlarge")
+		}
+	}
+	if args, ok := get(Extend{}.Key()); ok {
+		if o.extend, err = parseBool(args[0]); err != nil {
+			return o, errors.WithMessage(err, "extend")
+		}
+	}
+	if args, ok := get(Crop{}.Key()); ok {
+		if len(args) < 2 {
+			return o, errors.New("crop: width and height are required")
+		}
+		if o.cropWidth, err = strconv.ParseFloat(args[0], 64); err != nil {
+			return o, errors.WithMessage(err, "crop width")
+		}
+		if o.cropHeight, err = strconv.ParseFloat(args[1], 64); err != nil {
+			return o, errors.WithMessage(err, "crop height")
+		}
+	}
+	if args, ok := get(Padding{}.Key()); ok {
+		// css-like: missing right is top, missing bottom is top, missing left is right
+		var values [4]int
+		var set [4]bool
+		for i := 0; i < len(args) && i < 4; i++ {
+			if args[i] == "" {
+				continue
+			}
+			if values[i], err = strconv.Atoi(args[i]); err != nil {
+				return o, errors.WithMessage(err, "padding")
+			}
+			set[i] = true
+		}
+		o.padding = values
+		if !set[1] {
+			o.padding[1] = o.padding[0]
+		}
+		if !set[2] {
+			o.padding[2] = o.padding[0]
+		}
+		if !set[3] {
+			o.padding[3] = o.padding[1]
+		}
+	}
+	if args, ok := get(Rotate{}.Key()); ok {
+		if o.angle, err = strconv.Atoi(args[0]); err != nil {
+			return o, errors.WithMessage(err, "rotate")
+		}
+		if o.angle%90 != 0 {
+			return o, optionError(Rotate{}.Key(), o.angle, "must be a multiple of 90")
+		}
+		o.angle = (o.angle%360 + 360) % 360
+	}
+
+	return o, nil
+}
+
+// parseBool parses booleans the way imgproxy does.
+func parseBool(s string) (bool, error) {
+	switch s {
+	case "1", "t", "true":
+		return true, nil
+	case "0", "f", "false":
+		return false, nil
+	}
+	return false, errors.Errorf("invalid boolean %q", s)
+}
+
+// Geometry calculates the intermediate and resulting image sizes for a source image of the given size (see ResultSize).
+func (u *Url) Geometry(srcWidth int, srcHeight int) (Geometry, error) {
+	var g Geometry
+	if srcWidth <= 0 || srcHeight <= 0 {
+		return g, errors.Errorf("invalid source size %dx%d", srcWidth, srcHeight)
+	}
+	o, err := u.sizeOptions()
+	if err != nil {
+		return g, err
+	}
+
+	g.Angle = o.angle
+	g.RotatedWidth, g.RotatedHeight = srcWidth, srcHeight
+	if g.Angle == 90 || g.Angle == 270 {
+		g.RotatedWidth, g.RotatedHeight = srcHeight, srcWidth
+	}
+
+	g.CropWidth, g.CropHeight = g.RotatedWidth, g.RotatedHeight
+	if w := cropSize(g.RotatedWidth, o.cropWidth); w > 0 && w < g.CropWidth {
+		g.CropWidth = w
+	}
+	if h := cropSize(g.RotatedHeight, o.cropHeight); h > 0 && h < g.CropHeight {
+		g.CropHeight = h
+	}
+
+	wscale, hscale := scale(g.CropWidth, g.CropHeight, o)
+	g.ScaledWidth = scaleSize(g.CropWidth, wscale)
+	g.ScaledHeight = scaleSize(g.CropHeight, hscale)
+
+	// crop the resized image to the requested size
+	resultWidth, resultHeight := scaleSize(o.width, o.dpr), scaleSize(o.height, o.dpr)
+	cropWidth, cropHeight := resultWidth, resultHeight
+	if o.resizingType == ResizingTypeFillDown && !o.enlarge && cropWidth > 0 && cropHeight > 0 {
+		// keep the requested aspect ratio when the image is smaller than requested
+		diffW := float64(cropWidth) / float64(g.ScaledWidth)
+		diffH := float64(cropHeight) / float64(g.ScaledHeight)
+		switch {
+		case diffW > diffH && diffW > 1:
+			cropHeight = scaleSize(g.ScaledWidth, float64(cropHeight)/float64(cropWidth))
+			cropWidth = g.ScaledWidth
+		case diffH > diffW && diffH > 1:
+			cropWidth = scaleSize(g.ScaledHeight, float64(cropWidth)/float64(cropHeight))
+			cropHeight = g.ScaledHeight
+		}
+	}
+	g.ResultCropWidth, g.ResultCropHeight = g.ScaledWidth, g.ScaledHeight
+	if cropWidth > 0 && cropWidth < g.ResultCropWidth {
+		g.ResultCropWidth = cropWidth
+	}
+	if cropHeight > 0 && cropHeight < g.ResultCropHeight {
+		g.ResultCropHeight = cropHeight
+	}
+
+	g.ExtendedWidth, g.ExtendedHeight = g.ResultCropWidth, g.ResultCropHeight
+	if o.extend {
+		if resultWidth > g.ExtendedWidth {
+			g.ExtendedWidth = resultWidth
+		}
+		if resultHeight > g.ExtendedHeight {
+			g.ExtendedHeight = resultHeight
+		}
+	}
+
+	for i, p := range o.padding {
+		g.Padding[i] = scaleSize(p, o.dpr)
+	}
+	g.Width = g.ExtendedWidth + g.Padding[1] + g.Padding[3]
+	g.Height = g.ExtendedHeight + g.Padding[0] + g.Padding[2]
+
+	return g, nil
+}
+
+// cropSize returns the crop size in pixels: absolute when crop >= 1, relative to size when crop < 1, 0 for no crop.
+func cropSize(size int, crop float64) int {
+	switch {
+	case crop <= 0:
+		return 0
+	case crop >= 1:
+		return int(crop)
+	default:
+		s := int(math.Round(float64(size) * crop))
+		if s < 1 {
+			s = 1
+		}
+		return s
+	}
+}
+
+// scale returns the horizontal and vertical scale factors the same way imgproxy calculates them.
+func scale(width int, height int, o sizeOptions) (float64, float64) {
+	wshrink, hshrink := 1.0, 1.0
+	srcW, srcH := float64(width), float64(height)
+	dstW, dstH := float64(o.width), float64(o.height)
+
+	if o.width == 0 {
+		dstW = srcW
+	}
+	if dstW != srcW {
+		wshrink = srcW / dstW
+	}
+	if o.height == 0 {
+		dstH = srcH
+	}
+	if dstH != srcH {
+		hshrink = srcH / dstH
+	}
+
+	if wshrink != 1 || hshrink != 1 {
+		rt := o.resizingType
+		if rt == ResizingTypeAuto {
+			srcD, dstD := srcW-srcH, dstW-dstH
+			if (srcD >= 0 && dstD >= 0) || (srcD < 0 && dstD < 0) {
+				rt = ResizingTypeFill
+			} else {
+				rt = ResizingTypeFit
+			}
+		}
+
+		switch {
+		case o.width == 0 && rt != ResizingTypeForce:
+			wshrink = hshrink
+		case o.height == 0 && rt != ResizingTypeForce:
+			hshrink = wshrink
+		case rt == ResizingTypeFit:
+			wshrink = math.Max(wshrink, hshrink)
+			hshrink = wshrink
+		case rt == ResizingTypeFill || rt == ResizingTypeFillDown:
+			wshrink = math.Min(wshrink, hshrink)
+			hshrink = wshrink
+		}
+	}
+
+	wshrink /= o.dpr
+	hshrink /= o.dpr
+
+	if !o.enlarge {
+		if minShrink := math.Min(wshrink, hshrink); minShrink < 1 {
+			wshrink /= minShrink
+			hshrink /= minShrink
+		}
+	}
+
+	return 1 / wshrink, 1 / hshrink
+}
+
+// scaleSize scales size rounding to the nearest integer. Non-zero sizes are never scaled below 1.
+func scaleSize(size int, scale float64) int {
+	if size == 0 {
+		return 0
+	}
+	s := int(math.Round(float64(size) * scale))
+	if s < 1 {
+		s = 1
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+package imgproxyurl
+
+import (
+	"testing"
+)
+
+func TestUrl_ResultSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    []Option
+		srcWidth   int
+		srcHeight  int
+		wantWidth  int
+		wantHeight int
+		wantErr    bool
+	}{
+		{name: "no options", srcWidth: 1000, srcHeight: 500, wantWidth: 1000, wantHeight: 500},
+		{name: "fit", options: []Option{Width{200}, Height{200}}, srcWidth: 1000, srcHeight: 500, wantWidth: 200, wantHeight: 100},
+		{name: "fill", options: []Option{Width{200}, Height{200}, ResizingType{ResizingTypeFill}}, srcWidth: 1000, srcHeight: 500, wantWidth: 200, wantHeight: 200},
+		{name: "force", options: []Option{Width{200}, Height{50}, ResizingType{ResizingTypeForce}}, srcWidth: 1000, srcHeight: 500, wantWidth: 200, wantHeight: 50},
+		{name: "auto, same orientation", options: []Option{Width{300}, Height{200}, ResizingType{ResizingTypeAuto}}, srcWidth: 1000, srcHeight: 500, wantWidth: 300, wantHeight: 200},
+		{name: "auto, different orientation", options: []Option{Width{200}, Height{300}, ResizingType{ResizingTypeAuto}}, srcWidth: 1000, srcHeight: 500, wantWidth: 200, wantHeight: 100},
+		{name: "width only", options: []Option{Width{300}}, srcWidth: 1000, srcHeight: 500, wantWidth: 300, wantHeight: 150},
+		{name: "height only", options: []Option{Height{100}, ResizingType{ResizingTypeFill}}, srcWidth: 1000, srcHeight: 500, wantWidth: 200, wantHeight: 100},
+		{name: "no enlarge", options: []Option{Width{2000}}, srcWidth: 1000, srcHeight: 500, wantWidth: 1000, wantHeight: 500},
+		{name: "no enlarge, fill", options: []Option{Width{2000}, Height{2000}, ResizingType{ResizingTypeFill}}, srcWidth: 1000, srcHeight: 500, wantWidth: 1000, wantHeight: 500},
+		{name: "enlarge", options: []Option{Width{2000}, Enlarge{true}}, srcWidth: 1000, srcHeight: 500, wantWidth: 2000, wantHeight: 1000},
+		{name: "fill-down", options: []Option{Width{200}, Height{200}, ResizingType{ResizingTypeFillDown}}, srcWidth: 100, srcHeight: 50, wantWidth: 50, wantHeight: 50},
+		{name: "fill-down, large source", options: []Option{Width{200}, Height{200}, ResizingType{ResizingTypeFillDown}}, srcWidth: 1000, srcHeight: 500, wantWidth: 200, wantHeight: 200},
+		{name: "dpr", options: []Option{Width{200}, Height{200}, Dpr{2}}, srcWidth: 1000, srcHeight: 500, wantWidth: 400, wantHeight: 200},
+		{name: "dpr, fill", options: []Option{Width{200}, Height{200}, Dpr{2}, ResizingType{ResizingTypeFill}}, srcWidth: 1000, srcHeight: 500, wantWidth: 400, wantHeight: 400},
+		{name: "dpr doesn't enlarge", options: []Option{Width{200}, Dpr{3}}, srcWidth: 500, srcHeight: 500, wantWidth: 500, wantHeight: 500},
+		{name: "extend", options: []Option{Width{2000}, Height{2000}, Extend{Extend: true}}, srcWidth: 1000, srcHeight: 500, wantWidth: 2000, wantHeight: 2000},
+		{name: "extend, fit", options: []Option{Width{200}, Height{200}, Extend{Extend: true}}, srcWidth: 1000, srcHeight: 500, wantWidth: 200, wantHeight: 200},
+		{name: "padding", options: []Option{Width{200}, Height{200}, Padding{10, 20, 30, 40}}, srcWidth: 1000, srcHeight: 500, wantWidth: 260, wantHeight: 140},
+		{name: "css-like padding", options: []Option{Raw{OptionKey: "pd", Parameters: []interface{}{10}}}, srcWidth: 100, srcHeight: 100, wantWidth: 120, wantHeight: 120},
+		{name: "padding, dpr", options: []Option{Padding{10, 10, 10, 10}, Dpr{2}}, srcWidth: 100, srcHeight: 100, wantWidth: 140, wantHeight: 140},
+		{name: "rotate", options: []Option{Width{200}, Height{200}, Rotate{90}}, srcWidth: 1000, srcHeight: 500, wantWidth: 100, wantHeight: 200},
+		{name: "rotate negative", options: []Option{Rotate{-90}}, srcWidth: 1000, srcHeight: 500, wantWidth: 500, wantHeight: 1000},
+		{name: "rotate 180", options: []Option{Rotate{180}}, srcWidth: 1000, srcHeight: 500, wantWidth: 1000, wantHeight: 500},
+		{name: "relative crop", options: []Option{Crop{Width: 0.5, Height: 0}, Width{200}, Height{200}}, srcWidth: 1000, srcHeight: 500, wantWidth: 200, wantHeight: 200},
+		{name: "absolute crop", options: []Option{Crop{Width: 300, Height: 100}}, srcWidth: 1000, srcHeight: 500, wantWidth: 300, wantHeight: 100},
+		{name: "crop larger than source", options: []Option{Crop{Width: 3000, Height: 0}}, srcWidth: 1000, srcHeight: 500, wantWidth: 1000, wantHeight: 500},
+		{name: "resize meta-option", options: []Option{Resize{ResizingType: ResizingTypeFill, Width: 100, Height: 100}}, srcWidth: 1000, srcHeight: 500, wantWidth: 100, wantHeight: 100},
+		{name: "invalid source size", srcWidth: 0, srcHeight: 500, wantErr: true},
+		{name: "malformed width", options: []Option{Raw{OptionKey: "w", Parameters: []interface{}{"abc"}}}, srcWidth: 100, srcHeight: 100, wantErr: true},
+		{name: "malformed rotate", options: []Option{Rotate{45}}, srcWidth: 100, srcHeight: 100, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := New("local:///a.jpg", tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, h, err := u.ResultSize(tt.srcWidth, tt.srcHeight)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResultSize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if w != tt.wantWidth || h != tt.wantHeight {
+				t.Errorf("ResultSize() = %dx%d, want %dx%d", w, h, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package imgproxyurl
+
+import (
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+)
+
+// SrcsetCandidate is a single image candidate of a srcset attribute.
+type SrcsetCandidate struct {
+	// Url is the signed imgproxy url.
+	Url string
+	// Descriptor is either a width descriptor (e.g. "200w") or a pixel density descriptor (e.g. "2x").
+	Descriptor string
+}
+
+// Srcset is a list of image candidates. String returns it as a value for the srcset attribute.
+type Srcset []SrcsetCandidate
+
+func (s Srcset) String() string {
+	candidates := make([]string, 0, len(s))
+	for _, candidate := range s {
+		candidates = append(candidates, candidate.Url+" "+candidate.Descriptor)
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// SrcsetWidths returns a srcset with a candidate of each of the widths (a width ladder).
+// Every candidate is u with the Width option set, so it keeps all the other options, key, salt, endpoint, etc.
+func (u *Url) SrcsetWidths(widths ...int) (Srcset, error) {
+	srcset := make(Srcset, 0, len(widths))
+	for _, width := range widths {
+		if width <= 0 {
+			return nil, optionError(Width{}.Key(), width, "srcset widths must be greater than 0")
+		}
+		candidate, err := u.WithOptions(Width{width})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "width %d", width)
+		}
+		candidateUrl, err := candidate.Build()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "width %d", width)
+		}
+		srcset = append(srcset, SrcsetCandidate{Url: candidateUrl, Descriptor: strconv.Itoa(width) + "w"})
+	}
+	return srcset, nil
+}
+
+// SrcsetDensities returns a srcset with a candidate of each of the pixel densities for an image of the given css width.
+// Every candidate is u with the Width and Dpr options set. Zero width keeps the width of u.
+func (u *Url) SrcsetDensities(width int, densities ...int) (Srcset, error) {
+	srcset := make(Srcset, 0, len(densities))
+	for _, density := range densities {
+		if density <= 0 {
+			return nil, optionError(Dpr{}.Key(), density, "srcset densities must be greater than 0")
+		}
+		options := []Option{Dpr{density}}
+		if width > 0 {
+			options = append(options, Width{width})
+		}
+		candidate, err := u.WithOptions(options...)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "density %d", density)
+		}
+		candidateUrl, err := candidate.Build()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "density %d", density)
+		}
+		srcset = append(srcset, SrcsetCandidate{Url: candidateUrl, Descriptor: strconv.Itoa(density) + "x"})
+	}
+	return srcset, nil
+}
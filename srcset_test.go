@@ -0,0 +1,51 @@
+package imgproxyurl
+
+import (
+	"testing"
+)
+
+func TestUrl_SrcsetWidths(t *testing.T) {
+	u, err := New("local:///a.jpg", Width{100}, Height{0}, Format{"webp"}, Endpoint{"https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcset, err := u.SrcsetWidths(200, 400)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "https://example.com/insecure/h:0/w:200/bG9jYWw6Ly8vYS5qcGc.webp 200w, https://example.com/insecure/h:0/w:400/bG9jYWw6Ly8vYS5qcGc.webp 400w"
+	if got := srcset.String(); got != want {
+		t.Errorf("SrcsetWidths() = %v, want %v", got, want)
+	}
+
+	if _, err := u.SrcsetWidths(200, 0); err == nil {
+		t.Errorf("SrcsetWidths() expected an error")
+	}
+}
+
+func TestUrl_SrcsetDensities(t *testing.T) {
+	u, err := New("local:///a.jpg", Key{testKey}, Salt{testSalt}, SignatureSize{4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcset, err := u.SrcsetDensities(300, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(srcset) != 2 || srcset[0].Descriptor != "1x" || srcset[1].Descriptor != "2x" {
+		t.Fatalf("SrcsetDensities() = %v", srcset)
+	}
+	for i, candidate := range srcset {
+		if err := u.Verify(candidate.Url); err != nil {
+			t.Errorf("SrcsetDensities() candidate %d is not signed: %v", i, err)
+		}
+	}
+	want, _ := u.WithOptions(Width{300}, Dpr{2})
+	if srcset[1].Url != want.String() {
+		t.Errorf("SrcsetDensities() 2x = %v, want %v", srcset[1].Url, want.String())
+	}
+
+	if _, err := u.SrcsetDensities(300, 0); err == nil {
+		t.Errorf("SrcsetDensities() expected an error")
+	}
+}
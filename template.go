@@ -0,0 +1,136 @@
+package imgproxyurl
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"html/template"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+)
+
+// FuncMap returns html/template functions which build urls derived from base:
+//
+//	imgproxy SOURCE [KEY VALUE]...
+//	imgproxySrcset SOURCE WIDTHS [KEY VALUE]...
+//	imgproxyPicture SOURCE ALT FORMATS [KEY VALUE]...
+//
+// KEY is a processing option key (e.g. "w") and VALUE is its arguments: a single value or a colon-separated string
+// (e.g. "c" "100:200"). The "format" key sets the resulting image format (see Format).
+// WIDTHS is a width ladder (see Url.SrcsetWidths) and FORMATS is a list of formats (see Picture.Formats),
+// either slices or comma-separated strings.
+//
+// The functions return template.URL, template.Srcset and template.HTML values respectively, so html/template
+// doesn't escape the urls again.
+//
+//	<img src="{{ imgproxy .Image "w" 200 "h" 100 "rt" "fill" }}" srcset="{{ imgproxySrcset .Image "200,400" "rt" "fill" }}">
+func FuncMap(base *Url) template.FuncMap {
+	return template.FuncMap{
+		"imgproxy": func(sourceUrl string, pairs ...interface{}) (template.URL, error) {
+			u, err := templateUrl(base, sourceUrl, pairs)
+			if err != nil {
+				return "", err
+			}
+			result, err := u.Build()
+			if err != nil {
+				return "", err
+			}
+			return template.URL(result), nil
+		},
+		"imgproxySrcset": func(sourceUrl string, widths interface{}, pairs ...interface{}) (template.Srcset, error) {
+			u, err := templateUrl(base, sourceUrl, pairs)
+			if err != nil {
+				return "", err
+			}
+			ws, err := templateInts(widths)
+			if err != nil {
+				return "", err
+			}
+			srcset, err := u.SrcsetWidths(ws...)
+			if err != nil {
+				return "", err
+			}
+			return template.Srcset(srcset.String()), nil
+		},
+		"imgproxyPicture": func(sourceUrl string, alt string, formats interface{}, pairs ...interface{}) (template.HTML, error) {
+			u, err := templateUrl(base, sourceUrl, pairs)
+			if err != nil {
+				return "", err
+			}
+			fs, err := templateStrings(formats)
+			if err != nil {
+				return "", err
+			}
+			formatNames := make([]FormatName, len(fs))
+			for i, f := range fs {
+				formatNames[i] = FormatName(f)
+			}
+			picture, err := Picture{Url: u, Formats: formatNames, Alt: alt}.HTML()
+			if err != nil {
+				return "", err
+			}
+			return template.HTML(picture), nil
+		},
+	}
+}
+
+// TextFuncMap returns the functions of FuncMap for text/template.
+func TextFuncMap(base *Url) texttemplate.FuncMap {
+	return texttemplate.FuncMap(FuncMap(base))
+}
+
+func templateUrl(base *Url, sourceUrl string, pairs []interface{}) (*Url, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.Errorf("options must be key/value pairs, got %d arguments", len(pairs))
+	}
+
+	options := []Option{SourceUrl{sourceUrl}}
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok || key == "" {
+			return nil, errors.Errorf("option key must be a non-empty string, got %v", pairs[i])
+		}
+		if key == "format" {
+			options = append(options, Format{FormatName(fmt.Sprint(pairs[i+1]))})
+			continue
+		}
+		options = append(options, Raw{OptionKey: key, Parameters: []interface{}{pairs[i+1]}})
+	}
+
+	return base.WithOptions(options...)
+}
+
+func templateInts(value interface{}) ([]int, error) {
+	switch v := value.(type) {
+	case []int:
+		return v, nil
+	case string:
+		var result []int
+		for _, s := range strings.Split(v, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				return nil, errors.WithMessagef(err, "widths %q", v)
+			}
+			result = append(result, n)
+		}
+		return result, nil
+	}
+	return nil, errors.Errorf("widths must be []int or a comma-separated string, got %T", value)
+}
+
+func templateStrings(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case []string:
+		return v, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		result := strings.Split(v, ",")
+		for i := range result {
+			result[i] = strings.TrimSpace(result[i])
+		}
+		return result, nil
+	}
+	return nil, errors.Errorf("formats must be []string or a comma-separated string, got %T", value)
+}
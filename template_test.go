@@ -0,0 +1,100 @@
+package imgproxyurl
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"testing"
+	texttemplate "text/template"
+)
+
+func TestFuncMap(t *testing.T) {
+	base, err := New("", Endpoint{"https://example.com"}, Key{testKey}, Salt{testSalt}, SignatureSize{4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := func(options ...Option) string {
+		u, err := base.WithOptions(options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u.String()
+	}
+	data := map[string]interface{}{"Image": "local:///o/t/otRO1jl3IUVa.jpg", "Widths": []int{200, 400}}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{name: "url",
+			template: `<img src="{{ imgproxy .Image "w" 200 "h" 100 "rt" "fill" "format" "webp" }}">`,
+			want:     `<img src="` + signed(SourceUrl{"local:///o/t/otRO1jl3IUVa.jpg"}, Width{200}, Height{100}, ResizingType{ResizingTypeFill}, Format{"webp"}) + `">`},
+		{name: "multiple arguments",
+			template: `<img src="{{ imgproxy .Image "c" "100:200" }}">`,
+			want:     `<img src="` + signed(SourceUrl{"local:///o/t/otRO1jl3IUVa.jpg"}, Crop{Width: 100, Height: 200}) + `">`},
+		{name: "srcset",
+			template: `<img srcset="{{ imgproxySrcset .Image .Widths "rt" "fit" }}">`,
+			want: `<img srcset="` + signed(SourceUrl{"local:///o/t/otRO1jl3IUVa.jpg"}, ResizingType{ResizingTypeFit}, Width{200}) + ` 200w, ` +
+				signed(SourceUrl{"local:///o/t/otRO1jl3IUVa.jpg"}, ResizingType{ResizingTypeFit}, Width{400}) + ` 400w">`},
+		{name: "srcset from string",
+			template: `<img srcset="{{ imgproxySrcset .Image "200" }}">`,
+			want:     `<img srcset="` + signed(SourceUrl{"local:///o/t/otRO1jl3IUVa.jpg"}, Width{200}) + ` 200w">`},
+		{name: "picture",
+			template: `{{ imgproxyPicture .Image "<alt>" "webp,jpg" "w" 100 }}`,
+			want: `<picture><source type="image/webp" srcset="` + signed(SourceUrl{"local:///o/t/otRO1jl3IUVa.jpg"}, Width{100}, Format{"webp"}) + `">` +
+				`<img src="` + signed(SourceUrl{"local:///o/t/otRO1jl3IUVa.jpg"}, Width{100}, Format{"jpg"}) + `" alt="&lt;alt&gt;"></picture>`},
+		{name: "odd arguments", template: `{{ imgproxy .Image "w" }}`, wantErr: true},
+		{name: "non-string key", template: `{{ imgproxy .Image 1 2 }}`, wantErr: true},
+		{name: "malformed widths", template: `{{ imgproxySrcset .Image "a,b" }}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := template.Must(template.New("").Funcs(FuncMap(base)).Parse(tt.template))
+			var b bytes.Buffer
+			err := tmpl.Execute(&b, data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && b.String() != tt.want {
+				t.Errorf("Execute() =\n%v\nwant\n%v", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestTextFuncMap(t *testing.T) {
+	base, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := texttemplate.Must(texttemplate.New("").Funcs(TextFuncMap(base)).Parse(`{{ imgproxy "local:///a.jpg" "w" 200 }}`))
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "/insecure/w:200/bG9jYWw6Ly8vYS5qcGc"; got != want {
+		t.Errorf("Execute() = %v, want %v", got, want)
+	}
+}
+
+func TestFuncMap_buildErrors(t *testing.T) {
+	base, err := New("", RequireSignature{true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{
+		`{{ imgproxy "local:///a.jpg" "w" 200 }}`,
+		`{{ imgproxySrcset "local:///a.jpg" "200,400" }}`,
+		`{{ imgproxyPicture "local:///a.jpg" "" "webp,jpg" }}`,
+	} {
+		t.Run(text, func(t *testing.T) {
+			tmpl := template.Must(template.New("").Funcs(FuncMap(base)).Parse(text))
+			var b bytes.Buffer
+			if err := tmpl.Execute(&b, nil); !errors.Is(err, ErrSignatureRequired) {
+				t.Errorf("Execute() error = %v, want %v", err, ErrSignatureRequired)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package imgproxyurl
 
 import (
+	"crypto/aes"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
@@ -8,46 +9,99 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"net/url"
-	"sort"
+	"reflect"
 	"strings"
 )
 
-var std = &Url{
-	options: make(map[string]string),
-}
-
 type Url struct {
-	key            []byte
-	salt           []byte
-	options        map[string]string
-	sourceUrl      string
-	plainSourceUrl bool
-	format         string
-	endpoint       string
-	signatureSize  int
+	key                []byte
+	salt               []byte
+	keyring            *Keyring
+	signer             Signer
+	requireSignature   bool
+	insecure           bool
+	options            processingOptions
+	sourceUrl          string
+	plainSourceUrl     bool
+	encryptedSourceUrl bool
+	encryptionKey      []byte
+	format             string
+	formatAsOption     bool
+	endpoint           string
+	signatureSize      int
+	strict             bool
+	optionsOrder       OptionsOrderName
+	compact            bool
 }
 
+// New creates a url for sourceUrl with the global settings and the options passed (see Default).
 func New(sourceUrl string, options ...Option) (*Url, error) {
-	result, err := std.WithOptions(options...)
-	if err != nil {
-		return nil, err
-	}
-	result.sourceUrl = sourceUrl
-	return result, nil
+	return Default().New(sourceUrl, options...)
 }
 
 func (u *Url) WithOptions(options ...Option) (*Url, error) {
 	return u.clone(options)
 }
 
+// SourceUrl returns the source image url.
+func (u *Url) SourceUrl() string {
+	return u.sourceUrl
+}
+
+// formatOptionKey is the key of the format processing option (see FormatAsOption).
+const formatOptionKey = "f"
+
+// Format returns the resulting image format set with the Format option.
+func (u *Url) Format() string {
+	return u.format
+}
+
+// Endpoint returns the imgproxy endpoint the url is built for.
+func (u *Url) Endpoint() string {
+	return u.endpoint
+}
+
+// Options returns the processing options of the url as they appear in the path (e.g. "w:200").
+func (u *Url) Options() []string {
+	return u.optionParts()
+}
+
+// Option returns the arguments of the processing option with the given key (e.g. "200" for "w").
+func (u *Url) Option(key string) (string, bool) {
+	return u.options.get(key)
+}
+
+// Signed reports whether the url is signed, i.e. either a custom Signer or both key and salt are set.
+func (u *Url) Signed() bool {
+	return u.activeSigner() != nil
+}
+
+// String returns the url (see Build). It's meant for printing and debugging:
+//
+// WARNING: String returns an empty string when Build fails (RequireSignature without a key and salt,
+// a half-configured key/salt pair, a Signer error), so printing or templating it silently produces empty urls.
+// Use Build wherever the url is used (the template functions, Srcset and Picture already do and return the error).
 func (u *Url) String() string {
+	result, _ := u.Build()
+	return result
+}
+
+// Build returns the url signed with the custom Signer (see CustomSigner) or with the key and salt.
+// Urls without a Signer, key and salt get the "insecure" signature unless RequireSignature is set (ErrSignatureRequired).
+// A half-configured key/salt pair is an error (ErrIncompleteKeySalt), use Insecure for unsigned urls.
+// Signer errors are returned as is.
+func (u *Url) Build() (string, error) {
+	if err := u.checkSigner(); err != nil {
+		return "", err
+	}
 	p := u.getPath()
 
-	var signature string
-	if u.key == nil || u.salt == nil {
-		signature = "insecure"
-	} else {
-		signature = u.sign(p)
+	signature := insecureSignature
+	if signer := u.activeSigner(); signer != nil {
+		var err error
+		if signature, err = signer.Sign(p); err != nil {
+			return "", err
+		}
 	}
 
 	var result string
@@ -63,65 +117,133 @@ func (u *Url) String() string {
 		result = signedPath
 	}
 
-	return result
+	return result, nil
+}
+
+func sign(key []byte, salt []byte, signatureSize int, str string) string {
+	return base64.RawURLEncoding.EncodeToString(signatureBytes(key, salt, signatureSize, str))
 }
 
-func (u *Url) sign(str string) string {
-	mac := hmac.New(sha256.New, u.key)
-	mac.Write(u.salt)
+func signatureBytes(key []byte, salt []byte, signatureSize int, str string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(salt)
 	mac.Write([]byte(str))
 
-	size := u.signatureSize
+	size := signatureSize
 	if size == 0 {
 		size = 32
 	}
-	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:size])
+	return mac.Sum(nil)[:size]
 }
 
 func (u *Url) getPath() string {
-	var urlParts []string
-	for name, option := range u.options {
-		s := name
-		if option != "" {
-			s += ":" + option
-		}
-		urlParts = append(urlParts, s)
-	}
-	// sort url parts to make sure the resulting url is stable (to make unit test work)
-	sort.Slice(urlParts, func(i, j int) bool {
-		return urlParts[i] < urlParts[j]
-	})
-	urlParts = append(urlParts, u.encodeSourceUrl())
+	urlParts := append(u.optionParts(), u.encodeSourceUrl())
 	return "/" + strings.Join(urlParts, "/")
 }
 
+func (u *Url) optionParts() []string {
+	options := u.options
+	if u.formatAsOption && u.format != "" {
+		// parsed urls keep the format option where it was
+		options = options.clone().replace(formatOptionKey, u.format)
+	}
+	if u.compact {
+		options = options.compacted(u.optionsOrder)
+	}
+	return options.ordered(u.optionsOrder)
+}
+
 func (u *Url) encodeSourceUrl() string {
+	format := u.format
+	if u.formatAsOption {
+		format = ""
+	}
+
 	var encodedUrl string
-	if u.plainSourceUrl {
+	if u.encryptedSourceUrl {
+		// the encryption key is checked in applyOptions, so encryption can't fail here
+		encrypted, _ := encryptSourceUrl(u.encryptionKey, u.sourceUrl)
+		encodedUrl = "enc/" + encrypted
+		if format != "" {
+			encodedUrl += "." + format
+		}
+	} else if u.plainSourceUrl {
 		encodedUrl = "plain/" + url.QueryEscape(u.sourceUrl)
-		if u.format != "" {
-			encodedUrl += "@" + u.format
+		if format != "" {
+			encodedUrl += "@" + format
 		}
 	} else {
 		encodedUrl = base64.RawURLEncoding.EncodeToString([]byte(u.sourceUrl))
-		if u.format != "" {
-			encodedUrl += "." + u.format
+		if format != "" {
+			encodedUrl += "." + format
 		}
 	}
 	return encodedUrl
 }
 
 func (u *Url) applyOptions(options ...Option) error {
+	normalized := make([]Option, 0, len(options))
+	strict := u.strict
+	for _, option := range options {
+		option = normalizeOption(option)
+		if s, ok := option.(Strict); ok {
+			strict = s.Strict
+		}
+		normalized = append(normalized, option)
+	}
+	options = normalized
+
+	if strict {
+		for _, option := range options {
+			if option == nil {
+				return errors.New("nil option")
+			}
+			if v, ok := option.(Validator); ok {
+				if err := v.Validate(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
 	for _, option := range options {
 		switch option.(type) {
 		case ProcessingOption:
-			u.options[option.(ProcessingOption).Key()] = option.(ProcessingOption).String()
+			u.options = u.options.setOption(option.(ProcessingOption).Key(), option.(ProcessingOption).String())
 		case Format:
-			u.format = option.(Format).Format
+			u.format = string(option.(Format).Format)
+		case FormatAsOption:
+			u.formatAsOption = option.(FormatAsOption).AsOption
+			if !u.formatAsOption {
+				// drop the format option position kept by Parse
+				u.options = u.options.without(formatOptionKey)
+			}
 		case SourceUrl:
 			u.sourceUrl = option.(SourceUrl).Url
 		case PlainSourceUrl:
 			u.plainSourceUrl = option.(PlainSourceUrl).Plain
+		case EncryptedSourceUrl:
+			u.encryptedSourceUrl = option.(EncryptedSourceUrl).Encrypted
+		case EncryptionKey:
+			key := option.(EncryptionKey).Key
+			bytes, err := hex.DecodeString(key)
+			if err != nil {
+				return errors.WithMessage(err, "hexdecode")
+			}
+			if _, err := aes.NewCipher(bytes); err != nil {
+				return errors.WithMessage(err, "encryption key")
+			}
+
+			u.encryptionKey = bytes
+		case EncryptionKeyRaw:
+			key := option.(EncryptionKeyRaw).KeyRaw
+			if key != nil {
+				if _, err := aes.NewCipher(key); err != nil {
+					return errors.WithMessage(err, "encryption key")
+				}
+			}
+
+			u.encryptionKey = key
 		case Key:
 			key := option.(Key).Key
 			bytes, err := hex.DecodeString(key)
@@ -130,6 +252,9 @@ func (u *Url) applyOptions(options ...Option) error {
 			}
 
 			u.key = bytes
+			u.keyring = nil
+			u.signer = nil
+			u.insecure = false
 		case Salt:
 			salt := option.(Salt).Salt
 			bytes, err := hex.DecodeString(salt)
@@ -138,33 +263,105 @@ func (u *Url) applyOptions(options ...Option) error {
 			}
 
 			u.salt = bytes
+			u.keyring = nil
+			u.signer = nil
+			u.insecure = false
 		case KeyRaw:
 			u.key = option.(KeyRaw).KeyRaw
+			u.keyring = nil
+			u.signer = nil
+			u.insecure = false
 		case SaltRaw:
 			u.salt = option.(SaltRaw).SaltRaw
+			u.keyring = nil
+			u.signer = nil
+			u.insecure = false
+		case Keys:
+			keyring := option.(Keys).Keyring
+			u.keyring = keyring
+			u.signer = nil
+			u.insecure = false
+			if keyring == nil {
+				u.key, u.salt = nil, nil
+			} else {
+				active, ok := keyring.Active()
+				if !ok {
+					return errors.New("keyring has no key pairs")
+				}
+				u.key, u.salt = active.Key, active.Salt
+			}
+		case CustomSigner:
+			u.signer = option.(CustomSigner).Signer
+			u.insecure = false
+		case RequireSignature:
+			u.requireSignature = option.(RequireSignature).Require
+		case Insecure:
+			u.key, u.salt, u.keyring, u.signer = nil, nil, nil, nil
+			u.insecure = true
 		case Endpoint:
 			u.endpoint = option.(Endpoint).Endpoint
 		case SignatureSize:
 			u.signatureSize = option.(SignatureSize).SignatureSize
+		case Strict:
+			u.strict = option.(Strict).Strict
+		case OptionsOrder:
+			u.optionsOrder = option.(OptionsOrder).Order
+		case Compact:
+			u.compact = option.(Compact).Compact
+		default:
+			if strict && option != nil {
+				return errors.Errorf("unsupported option type %T", option)
+			}
 		}
 	}
 
+	if u.encryptedSourceUrl && u.encryptionKey == nil {
+		return errors.New("encrypted source url requires an encryption key")
+	}
+
 	return nil
 }
 
+// normalizeOption dereferences pointers to options, so that e.g. &Width{200} works the same way as Width{200}.
+// Nil options and nil pointers are normalized to nil.
+func normalizeOption(option Option) Option {
+	if option == nil {
+		return nil
+	}
+	v := reflect.ValueOf(option)
+	if v.Kind() != reflect.Ptr {
+		return option
+	}
+	if v.IsNil() {
+		return nil
+	}
+	if _, ok := option.(ProcessingOption); ok {
+		// keep custom processing options implemented with pointer receivers as they are
+		return option
+	}
+	return v.Elem().Interface()
+}
+
 func (u *Url) clone(addOptions []Option) (*Url, error) {
 	clone := &Url{
-		key:            u.key,
-		salt:           u.salt,
-		options:        make(map[string]string, len(u.options)),
-		sourceUrl:      u.sourceUrl,
-		plainSourceUrl: u.plainSourceUrl,
-		format:         u.format,
-		endpoint:       u.endpoint,
-		signatureSize:  u.signatureSize,
-	}
-	for key, value := range u.options {
-		clone.options[key] = value
+		key:                u.key,
+		salt:               u.salt,
+		keyring:            u.keyring,
+		signer:             u.signer,
+		requireSignature:   u.requireSignature,
+		insecure:           u.insecure,
+		options:            u.options.clone(),
+		sourceUrl:          u.sourceUrl,
+		plainSourceUrl:     u.plainSourceUrl,
+		encryptedSourceUrl: u.encryptedSourceUrl,
+		encryptionKey:      u.encryptionKey,
+		format:             u.format,
+		formatAsOption:     u.formatAsOption,
+		endpoint:           u.endpoint,
+		signatureSize:      u.signatureSize,
+		strict:             u.strict,
+		optionsOrder:       u.optionsOrder,
+		compact:            u.compact,
 	}
 	err := clone.applyOptions(addOptions...)
 	if err != nil {
@@ -174,14 +371,20 @@ func (u *Url) clone(addOptions []Option) (*Url, error) {
 	return clone, nil
 }
 
+// SetKeySalt sets the hex-encoded key and salt globally. On error the global settings are left unchanged.
 func SetKeySalt(key string, salt string) error {
-	return std.applyOptions(Key{key}, Salt{salt})
+	return updateDefault(Key{key}, Salt{salt})
 }
 
 func SetKeySaltRaw(key []byte, salt []byte) error {
-	return std.applyOptions(KeyRaw{key}, SaltRaw{salt})
+	return updateDefault(KeyRaw{key}, SaltRaw{salt})
 }
 
 func SetEndpoint(endpoint string) {
-	_ = std.applyOptions(Endpoint{endpoint})
+	_ = updateDefault(Endpoint{endpoint})
+}
+
+// SetStrict enables or disables validation of the options globally (see Strict).
+func SetStrict(strict bool) {
+	_ = updateDefault(Strict{strict})
 }
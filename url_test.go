@@ -12,7 +12,7 @@ func TestUrl_applyOptions(t *testing.T) {
 	type fields struct {
 		key            []byte
 		salt           []byte
-		options        map[string]string
+		options        processingOptions
 		sourceUrl      string
 		plainSourceUrl bool
 		format         string
@@ -35,27 +35,27 @@ func TestUrl_applyOptions(t *testing.T) {
 		}, wantErr: false},
 		{name: "malformed key/salt", fields: fields{}, args: args{options: []Option{Key{"key"}, Salt{"salt"}}}, want: &Url{}, wantErr: true},
 		{name: "options overriding", fields: fields{
-			options: map[string]string{
-				"z": "50",
-				"h": "100",
+			options: processingOptions{
+				{key: "z", value: "50"},
+				{key: "h", value: "100"},
 			},
 		}, args: args{options: []Option{Width{200}, Height{300}}}, want: &Url{
-			options: map[string]string{
-				"z": "50",
-				"h": "300",
-				"w": "200",
+			options: processingOptions{
+				{key: "z", value: "50"},
+				{key: "w", value: "200"},
+				{key: "h", value: "300"},
 			},
 		}, wantErr: false},
 		{name: "raw options", fields: fields{
-			options: map[string]string{
-				"z": "50",
-				"h": "100",
+			options: processingOptions{
+				{key: "z", value: "50"},
+				{key: "h", value: "100"},
 			},
 		}, args: args{options: []Option{Raw{OptionKey: "raw", Parameters: []interface{}{1, 2, "test"}}}}, want: &Url{
-			options: map[string]string{
-				"z":   "50",
-				"h":   "100",
-				"raw": "1:2:test",
+			options: processingOptions{
+				{key: "z", value: "50"},
+				{key: "h", value: "100"},
+				{key: "raw", value: "1:2:test"},
 			},
 		}, wantErr: false},
 	}
@@ -85,7 +85,7 @@ func TestUrl_clone(t *testing.T) {
 	type fields struct {
 		key            []byte
 		salt           []byte
-		options        map[string]string
+		options        processingOptions
 		sourceUrl      string
 		plainSourceUrl bool
 		format         string
@@ -105,8 +105,8 @@ func TestUrl_clone(t *testing.T) {
 		{name: "clone w/o additional options", fields: fields{
 			key:  []byte{1, 2, 3},
 			salt: []byte{2, 3, 4},
-			options: map[string]string{
-				"a": "100",
+			options: processingOptions{
+				{key: "a", value: "100"},
 			},
 			sourceUrl:      "https://example.com/test.jpg",
 			plainSourceUrl: true,
@@ -116,8 +116,8 @@ func TestUrl_clone(t *testing.T) {
 		}, args: args{}, want: &Url{
 			key:  []byte{1, 2, 3},
 			salt: []byte{2, 3, 4},
-			options: map[string]string{
-				"a": "100",
+			options: processingOptions{
+				{key: "a", value: "100"},
 			},
 			sourceUrl:      "https://example.com/test.jpg",
 			plainSourceUrl: true,
@@ -129,8 +129,8 @@ func TestUrl_clone(t *testing.T) {
 		{name: "clone w/ additional options", fields: fields{
 			key:  []byte{1, 2, 3},
 			salt: []byte{2, 3, 4},
-			options: map[string]string{
-				"a": "100",
+			options: processingOptions{
+				{key: "a", value: "100"},
 			},
 			sourceUrl:      "https://example.com/test.jpg",
 			plainSourceUrl: true,
@@ -140,9 +140,9 @@ func TestUrl_clone(t *testing.T) {
 		}, args: args{addOptions: []Option{Width{100}, SignatureSize{31}}}, want: &Url{
 			key:  []byte{1, 2, 3},
 			salt: []byte{2, 3, 4},
-			options: map[string]string{
-				"a": "100",
-				"w": "100",
+			options: processingOptions{
+				{key: "a", value: "100"},
+				{key: "w", value: "100"},
 			},
 			sourceUrl:      "https://example.com/test.jpg",
 			plainSourceUrl: true,
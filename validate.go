@@ -0,0 +1,41 @@
+package imgproxyurl
+
+import (
+	"fmt"
+)
+
+// Validator is implemented by options which can check their arguments.
+// In strict mode, Url validates every option implementing it before applying the options.
+type Validator interface {
+	Validate() error
+}
+
+// OptionError describes an invalid option argument.
+type OptionError struct {
+	// Key is the processing option key (e.g. "q" for Quality).
+	Key string
+	// Argument is the offending argument value.
+	Argument interface{}
+	// Reason explains why the argument is invalid.
+	Reason string
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("%s: invalid argument %v: %s", e.Key, e.Argument, e.Reason)
+}
+
+func optionError(key string, argument interface{}, reason string) error {
+	return &OptionError{Key: key, Argument: argument, Reason: reason}
+}
+
+func isHexColor(s string) bool {
+	if len(s) != 3 && len(s) != 6 {
+		return false
+	}
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+package imgproxyurl
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUrl_applyOptions_strict(t *testing.T) {
+	tests := []struct {
+		name    string
+		option  Option
+		wantKey string
+	}{
+		{name: "valid width", option: Width{100}},
+		{name: "negative width", option: Width{-1}, wantKey: "w"},
+		{name: "negative height", option: Height{-1}, wantKey: "h"},
+		{name: "quality", option: Quality{500}, wantKey: "q"},
+		{name: "dpr", option: Dpr{0}, wantKey: "dpr"},
+		{name: "rotate", option: Rotate{45}, wantKey: "rot"},
+		{name: "valid rotate", option: Rotate{-270}},
+		{name: "background alpha", option: BackgroundAlpha{3}, wantKey: "bga"},
+		{name: "background hex", option: BackgroundHex{"zzz"}, wantKey: "bg"},
+		{name: "valid background hex", option: BackgroundHex{"ffaa00"}},
+		{name: "resizing type", option: ResizingType{"stretch"}, wantKey: "rt"},
+		{name: "resizing algorithm", option: ResizingAlgorithm{"bicubic"}, wantKey: "ra"},
+		{name: "smart gravity with offsets", option: Gravity{Type: GravityTypeSmart, Offsets: GravityIntegerOffsets{1, 2}}, wantKey: "g"},
+		{name: "focus point w/o offsets", option: Gravity{Type: GravityTypeFocusPoint}, wantKey: "g"},
+		{name: "focus point out of range", option: Gravity{Type: GravityTypeFocusPoint, Offsets: GravityFloatOffsets{0.5, 1.5}}, wantKey: "g"},
+		{name: "valid focus point", option: Gravity{Type: GravityTypeFocusPoint, Offsets: GravityFloatOffsets{0.5, 0.5}}},
+		{name: "unknown gravity", option: Gravity{Type: "up"}, wantKey: "g"},
+		{name: "crop gravity", option: Crop{Width: 100, Height: 100, Gravity: &Gravity{Type: "up"}}, wantKey: "g"},
+		{name: "extend smart gravity", option: Extend{Extend: true, Gravity: &Gravity{Type: GravityTypeSmart}}, wantKey: "ex"},
+		{name: "padding", option: Padding{1, -1, 1, 1}, wantKey: "pd"},
+		{name: "presets", option: Presets{[]string{"a", "b:c"}}, wantKey: "pr"},
+		{name: "trim color", option: Trim{Threshold: 10, Color: "red"}, wantKey: "t"},
+		{name: "filename", option: Filename{"a/b.jpg"}, wantKey: "fn"},
+		{name: "watermark opacity", option: Watermark{Opacity: 1.5}, wantKey: "wm"},
+		{name: "watermark smart position", option: Watermark{Opacity: 1, Position: GravityTypeSmart}, wantKey: "wm"},
+		{name: "watermark scale", option: Watermark{Opacity: 1, Scale: -1}, wantKey: "wm"},
+		{name: "valid watermark", option: Watermark{Opacity: 0.5, Position: GravityTypeReplicate, Offsets: GravityIntegerOffsets{10, 10}, Scale: 0.3}},
+		{name: "replicate gravity", option: Gravity{Type: GravityTypeReplicate}, wantKey: "g"},
+		{name: "watermark url", option: WatermarkUrl{}, wantKey: "wmu"},
+		{name: "watermark text", option: WatermarkText{}, wantKey: "wmt"},
+		{name: "watermark size", option: WatermarkSize{Width: -1}, wantKey: "wms"},
+		{name: "watermark shadow", option: WatermarkShadow{-1}, wantKey: "wmsh"},
+		{name: "brightness", option: Brightness{256}, wantKey: "br"},
+		{name: "valid brightness", option: Brightness{-255}},
+		{name: "contrast", option: Contrast{-1}, wantKey: "co"},
+		{name: "saturation", option: Saturation{-0.5}, wantKey: "sa"},
+		{name: "adjust", option: Adjust{Brightness: &Brightness{10}, Saturation: &Saturation{-1}}, wantKey: "sa"},
+		{name: "monochrome", option: Monochrome{Intensity: 2}, wantKey: "mc"},
+		{name: "duotone", option: Duotone{Intensity: -1}, wantKey: "dt"},
+		{name: "colorize", option: Colorize{Opacity: 1.1}, wantKey: "col"},
+		{name: "jpeg quant table", option: JpegOptions{QuantTable: Int(9)}, wantKey: "jpgo"},
+		{name: "valid jpeg options", option: JpegOptions{Progressive: Bool(true), QuantTable: Int(8)}},
+		{name: "png quantization colors", option: PngOptions{Quantize: Bool(true), QuantizationColors: 1}, wantKey: "pngo"},
+		{name: "webp compression", option: WebpOptions{Compression: "zip"}, wantKey: "webpo"},
+		{name: "avif subsample", option: AvifOptions{Subsample: "yes"}, wantKey: "avifo"},
+		{name: "format quality", option: FormatQuality{map[FormatName]int{FormatJpeg: 80, FormatWebp: 101}}, wantKey: "fq"},
+		{name: "format quality format", option: FormatQuality{map[FormatName]int{"jpg ": 80}}, wantKey: "fq"},
+		{name: "format quality empty", option: FormatQuality{}, wantKey: "fq"},
+		{name: "format quality best", option: FormatQuality{map[FormatName]int{FormatBest: 80}}, wantKey: "fq"},
+		{name: "valid format quality", option: FormatQuality{map[FormatName]int{FormatJpeg: 80, FormatWebp: 70}}},
+		{name: "autoquality method", option: Autoquality{Method: "psnr"}, wantKey: "aq"},
+		{name: "autoquality min/max", option: Autoquality{Method: AutoqualityMethodDssim, MinQuality: 90, MaxQuality: 80}, wantKey: "aq"},
+		{name: "autoquality quality range", option: Autoquality{MaxQuality: 101}, wantKey: "aq"},
+		{name: "format", option: Format{"jepg"}, wantKey: "format"},
+		{name: "valid format", option: Format{FormatWebp}},
+		{name: "best format candidates", option: BestFormat{AllowedFormats: []FormatName{FormatWebp, FormatBest}}, wantKey: "bf"},
+		{name: "best format unknown candidate", option: BestFormat{AllowedFormats: []FormatName{"jepg"}}, wantKey: "bf"},
+		{name: "dpi", option: Dpi{-72}, wantKey: "dpi"},
+		{name: "signature size", option: SignatureSize{33}, wantKey: "signature_size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New("local:///a.jpg", Strict{true}, tt.option)
+			if tt.wantKey == "" {
+				if err != nil {
+					t.Errorf("New() error = %v", err)
+				}
+				return
+			}
+			var optionErr *OptionError
+			if !errors.As(err, &optionErr) {
+				t.Fatalf("New() error = %v, want *OptionError", err)
+			}
+			if optionErr.Key != tt.wantKey {
+				t.Errorf("New() error key = %v, want %v", optionErr.Key, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestUrl_applyOptions_notStrict(t *testing.T) {
+	u, err := New("local:///a.jpg", Quality{500})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := u.WithOptions(Strict{true}, Rotate{45}); err == nil {
+		t.Errorf("WithOptions() expected an error")
+	}
+	strict, err := u.WithOptions(Strict{true})
+	if err != nil {
+		t.Fatalf("WithOptions() error = %v", err)
+	}
+	if _, err := strict.WithOptions(Dpr{0}); err == nil {
+		t.Errorf("WithOptions() on a strict url expected an error")
+	}
+}
+
+type unknownOption struct{}
+
+type pointerOption struct{ value string }
+
+func (o *pointerOption) Key() string    { return "po" }
+func (o *pointerOption) String() string { return o.value }
+
+func TestUrl_applyOptions_unsupported(t *testing.T) {
+	var nilWidth *Width
+	tests := []struct {
+		name      string
+		option    Option
+		wantError bool
+	}{
+		{name: "unknown type", option: unknownOption{}, wantError: true},
+		{name: "pointer to unknown type", option: &unknownOption{}, wantError: true},
+		{name: "nil", option: nil, wantError: true},
+		{name: "nil pointer", option: nilWidth, wantError: true},
+		{name: "pointer to processing option", option: &Width{200}},
+		{name: "pointer to option", option: &Format{"png"}},
+		{name: "processing option with pointer receivers", option: &pointerOption{"1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New("local:///a.jpg", tt.option); err != nil {
+				t.Errorf("New() error = %v", err)
+			}
+			if _, err := New("local:///a.jpg", &Strict{true}, tt.option); (err != nil) != tt.wantError {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantError)
+			}
+		})
+	}
+
+	u, err := New("local:///a.jpg", &Width{200}, &Format{"png"}, &PlainSourceUrl{true}, &pointerOption{"1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u.String(), "/insecure/po:1/w:200/plain/local%3A%2F%2F%2Fa.jpg@png"; got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+}
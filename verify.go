@@ -0,0 +1,94 @@
+package imgproxyurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrInsecureSignature is returned when verifying a url which is not signed.
+	ErrInsecureSignature = errors.New("url is not signed")
+	// ErrMalformedSignature is returned when the signature of the url is missing or is not valid base64.
+	ErrMalformedSignature = errors.New("malformed signature")
+	// ErrSignatureMismatch is returned when the signature of the url doesn't match the expected one.
+	ErrSignatureMismatch = errors.New("signature mismatch")
+	// ErrNoKey is returned when verifying without a key or salt: an empty key and salt would accept forged signatures.
+	ErrNoKey = errors.New("key or salt is not set")
+)
+
+// Verify checks that rawUrl is signed with the given key and salt the same way imgproxy does.
+// signatureSize is the number of signature bytes to compare (IMGPROXY_SIGNATURE_SIZE), 0 means full-size signatures.
+// The endpoint is not signed, so rawUrl may contain any (or no) endpoint.
+func Verify(rawUrl string, key []byte, salt []byte, signatureSize int) error {
+	return verify(rawUrl, "", key, salt, signatureSize)
+}
+
+// Verify checks that rawUrl is signed with the key, salt and signature size of u.
+// When u has a Keyring (see Keys), a signature made with any of its pairs is accepted.
+// When u has a custom Signer, the signature is compared with the one the Signer returns.
+// Urls without a Signer, key and salt (including Insecure ones) can't verify anything and return ErrNoKey.
+func (u *Url) Verify(rawUrl string) error {
+	switch {
+	case u.activeSigner() == nil:
+		return ErrNoKey
+	case u.signer != nil:
+		return verifySigner(rawUrl, u.endpoint, u.signer)
+	case u.keyring != nil:
+		_, err := u.keyring.verify(rawUrl, u.endpoint, u.signatureSize)
+		return err
+	}
+	return verify(rawUrl, u.endpoint, u.key, u.salt, u.signatureSize)
+}
+
+func verify(rawUrl string, endpoint string, key []byte, salt []byte, signatureSize int) error {
+	if len(key) == 0 || len(salt) == 0 {
+		return ErrNoKey
+	}
+	if signatureSize < 0 || signatureSize > sha256.Size {
+		return errors.Errorf("invalid signature size %d", signatureSize)
+	}
+
+	signature, path, err := splitSignature(rawUrl, endpoint)
+	if err != nil {
+		return err
+	}
+	got, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.Wrap(ErrMalformedSignature, err.Error())
+	}
+
+	if !hmac.Equal(got, signatureBytes(key, salt, signatureSize, path)) {
+		return ErrSignatureMismatch
+	}
+
+	return nil
+}
+
+func verifySigner(rawUrl string, endpoint string, signer Signer) error {
+	signature, path, err := splitSignature(rawUrl, endpoint)
+	if err != nil {
+		return err
+	}
+	expected, err := signer.Sign(path)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
+
+// splitSignature returns the signature and the signed path of rawUrl.
+func splitSignature(rawUrl string, endpoint string) (string, string, error) {
+	_, signature, path := splitUrl(rawUrl, endpoint)
+	if signature == insecureSignature {
+		return "", "", ErrInsecureSignature
+	}
+	if signature == "" || path == "" {
+		return "", "", ErrMalformedSignature
+	}
+	return signature, path, nil
+}
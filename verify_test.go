@@ -0,0 +1,73 @@
+package imgproxyurl
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	key, _ := hex.DecodeString(testKey)
+	salt, _ := hex.DecodeString(testSalt)
+	const signed = "https://example.com/Yysx5pZ_gcWJbVQEHSp37U6r3swrZgFAygnHmbFK2VE/h:200/rt:fill/w:200/bG9jYWw6Ly8vby90L290Uk8xamwzSVVWYS5qcGc.png"
+	// signed with an empty key and salt
+	forged := "/" + sign(nil, nil, 0, "/w:200/bG9jYWw6Ly8vby90L290Uk8xamwzSVVWYS5qcGc") + "/w:200/bG9jYWw6Ly8vby90L290Uk8xamwzSVVWYS5qcGc"
+
+	type args struct {
+		rawUrl        string
+		signatureSize int
+		noKey         bool
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		{name: "valid", args: args{rawUrl: signed}, wantErr: nil},
+		{name: "valid w/o endpoint", args: args{rawUrl: "/Yysx5pZ_gcWJbVQEHSp37U6r3swrZgFAygnHmbFK2VE/h:200/rt:fill/w:200/bG9jYWw6Ly8vby90L290Uk8xamwzSVVWYS5qcGc.png"}, wantErr: nil},
+		{name: "truncated", args: args{rawUrl: "https://example.com/Yysx5pZ_gcU/h:200/rt:fill/w:200/bG9jYWw6Ly8vby90L290Uk8xamwzSVVWYS5qcGc.png", signatureSize: 8}, wantErr: nil},
+		{name: "truncated, size mismatch", args: args{rawUrl: signed, signatureSize: 8}, wantErr: ErrSignatureMismatch},
+		{name: "tampered", args: args{rawUrl: "https://example.com/Yysx5pZ_gcWJbVQEHSp37U6r3swrZgFAygnHmbFK2VE/h:300/rt:fill/w:200/bG9jYWw6Ly8vby90L290Uk8xamwzSVVWYS5qcGc.png"}, wantErr: ErrSignatureMismatch},
+		{name: "insecure", args: args{rawUrl: "https://example.com/insecure/w:200/bG9jYWw6Ly8vby90L290Uk8xamwzSVVWYS5qcGc.png"}, wantErr: ErrInsecureSignature},
+		{name: "malformed base64", args: args{rawUrl: "https://example.com/%%%/w:200/bG9jYWw6Ly8vby90L290Uk8xamwzSVVWYS5qcGc.png"}, wantErr: ErrMalformedSignature},
+		{name: "no path", args: args{rawUrl: "https://example.com/Yysx5pZ_gcWJbVQEHSp37U6r3swrZgFAygnHmbFK2VE"}, wantErr: ErrMalformedSignature},
+		{name: "forged w/ empty key and salt", args: args{rawUrl: forged, noKey: true}, wantErr: ErrNoKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, salt := key, salt
+			if tt.args.noKey {
+				key, salt = []byte{}, nil
+			}
+			if err := Verify(tt.args.rawUrl, key, salt, tt.args.signatureSize); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUrl_Verify(t *testing.T) {
+	u, err := New("local:///a.jpg", Width{100}, Key{testKey}, Salt{testSalt}, SignatureSize{16}, Endpoint{"https://example.com/imgproxy/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Verify(u.String()); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	other, _ := u.WithOptions(Salt{testKey})
+	if err := other.Verify(u.String()); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("Verify() error = %v, wantErr %v", err, ErrSignatureMismatch)
+	}
+
+	// signed with an empty key and salt
+	forged := "/" + sign(nil, nil, 0, "/w:100/bG9jYWw6Ly8vYS5qcGc") + "/w:100/bG9jYWw6Ly8vYS5qcGc"
+	for _, options := range [][]Option{{Key{""}, Salt{""}}, {Insecure{}}} {
+		unsigned, err := New("local:///a.jpg", options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := unsigned.Verify(forged); !errors.Is(err, ErrNoKey) {
+			t.Errorf("Verify() error = %v, wantErr %v", err, ErrNoKey)
+		}
+	}
+}